@@ -1,8 +1,12 @@
 package DataStructures
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
 	"math"
+	"sync"
+	"sync/atomic"
 )
 
 var (
@@ -15,268 +19,531 @@ type Unsigned interface {
 
 var defaultDegree = 4
 
-// DiskTree is just an interface
-type DiskTree[T comparable] interface {
-	Insert(data T)
-	Delete(data T) bool
-	Search(data T) bool
+// ErrDuplicateKey is returned by InsertUnique for a key that is already in the tree
+var ErrDuplicateKey = errors.New("btree: duplicate key")
+
+// DiskTree is just an interface. BTree implements it, a DiskBTree does
+// through AsDiskTree
+type DiskTree[K any, V any] interface {
+	Insert(key K)
+	Put(key K, value V)
+	Get(key K) (V, bool)
+	Delete(key K) (V, bool)
+	Search(key K) bool
 	Display()
+
+	// Ordered access over the leaves
+	Seek(key K) Iterator[K, V]
+	SeekLast(key K) Iterator[K, V]
+	Range(lo, hi K, loInclusive, hiInclusive bool) Iterator[K, V]
+	Min() (K, bool)
+	Max() (K, bool)
 }
 
-// BTree is our B+ Tree structure
-type BTree[T Ordered] struct {
-	degree uint16
-	root   *bNode[T]
+// BTree is our B+ Tree structure. Payloads only live in the leaves,
+// internal nodes hold separator keys used for routing.
+// It is safe for concurrent use, see B+TreeLatch.go
+type BTree[K any, V any] struct {
+	degree  uint16
+	root    *bNode[K, V]
+	compare func(a, b K) int // < 0 if a sorts before b, 0 if equal, > 0 after
+	counted bool             // internal nodes keep subtree counts, see newCountedBTree
+	gen     uint64           // bumped by Snapshot, nodes from older generations are shared
+	frozen  bool             // a Snapshot's view, never written and read without latches
+
+	// Structural changes since creation, see Stats
+	splits atomic.Uint64
+	merges atomic.Uint64
+
+	// mu is held shared by every point operation and exclusively by
+	// whole-tree operations such as Validate, BulkLoad and Display.
+	// rootLatch guards the 'root' pointer itself, see B+TreeLatch.go
+	mu        sync.RWMutex
+	rootLatch sync.RWMutex
 }
 
 // bNode represents a node in the B+ Tree
-type bNode[T Ordered] struct {
-	keys     []T         // sorted keys
-	children []*bNode[T] // child pointers (empty if leaf)
-	parent   *bNode[T]   // parent pointer
-	leaf     bool        // is leaf node?
-	next     *bNode[T]   // linked list pointer for leaves
+type bNode[K any, V any] struct {
+	keys     []K            // sorted keys
+	values   []V            // payloads, values[i] belongs to keys[i] (leaves only)
+	children []*bNode[K, V] // child pointers (empty if leaf)
+	counts   []int          // counts[i] is the number of keys below children[i] (internal nodes of counted trees only)
+	parent   *bNode[K, V]   // parent pointer
+	leaf     bool           // is leaf node?
+	next     *bNode[K, V]   // linked list pointer for leaves
+	prev     *bNode[K, V]   // backward linked list pointer for leaves
+	latch    sync.RWMutex   // guards this node while crabbing
+	version  uint64         // bumped whenever the keys or the prev link of a leaf change, see Cursor
+	gen      uint64         // snapshot generation the node was created in, see B+TreeSnapshot.go
+	stale    bool           // replaced by a private copy, no longer part of the live tree
 }
 
 // newBNode creates an empty leaf node
-func newBNode[T Ordered]() *bNode[T] {
-	return &bNode[T]{
-		keys:     make([]T, 0),
-		children: make([]*bNode[T], 0),
+func newBNode[K any, V any]() *bNode[K, V] {
+	return &bNode[K, V]{
+		keys:     make([]K, 0),
+		values:   make([]V, 0),
+		children: make([]*bNode[K, V], 0),
 		leaf:     true,
 		next:     nil,
+		prev:     nil,
 	}
 }
 
-// newBTree creates a new B+ Tree of the given degree
-func newBTree[T Ordered](degree uint16) *BTree[T] {
+// newNode creates an empty node that belongs to the live tree, not to any Snapshot
+func (b *BTree[K, V]) newNode(leaf bool) *bNode[K, V] {
+	node := newBNode[K, V]()
+	node.leaf = leaf
+	node.gen = b.gen
+	return node
+}
+
+// newBTree creates a new B+ Tree of the given degree, ordered by the natural order of K
+func newBTree[K Ordered, V any](degree uint16) *BTree[K, V] {
+	return newBTreeFunc[K, V](degree, cmp.Compare[K])
+}
+
+// newCountedBTree is newBTree with subtree counts in every internal node, so
+// Rank, Select and CountRange take O(log n) instead of a scan. Keeping them
+// exact costs concurrency: every insert of a new key and every delete changes
+// a count in each node from the root down, so it holds the whole path latched
+// until it is done and such writers run one at a time. Writers that only
+// replace values, and readers, are not held up. See B+TreeLatch.go
+func newCountedBTree[K Ordered, V any](degree uint16) *BTree[K, V] {
+	return newCountedBTreeFunc[K, V](degree, cmp.Compare[K])
+}
+
+// newCountedBTreeFunc is newCountedBTree ordered by 'compare'
+func newCountedBTreeFunc[K any, V any](degree uint16, compare func(a, b K) int) *BTree[K, V] {
+	b := newBTreeFunc[K, V](degree, compare)
+	b.counted = true
+	return b
+}
+
+// newBTreeFunc creates a new B+ Tree of the given degree ordered by 'compare',
+// for keys outside Ordered such as []byte, timestamps or multi-column keys
+func newBTreeFunc[K any, V any](degree uint16, compare func(a, b K) int) *BTree[K, V] {
 	// Start with an empty leaf as root
-	root := newBNode[T]()
+	root := newBNode[K, V]()
 	root.leaf = true
-	return &BTree[T]{
-		degree: degree,
-		root:   root,
+	return &BTree[K, V]{
+		degree:  degree,
+		root:    root,
+		compare: compare,
 	}
 }
 
 // Search checks if 'item' exists in the tree
-func (b *BTree[T]) Search(item T) bool {
-	if b.root == nil {
-		// Should not happen if we always init root, but safe check
-		return false
-	}
-	found, _, _ := b.searchNode(b.root, item)
+func (b *BTree[K, V]) Search(item K) bool {
+	_, found := b.Get(item)
 	return found
 }
 
-// searchNode returns:
-//
-//	(found=true, node, index) if 'item' exists at node.keys[index]
-//	(found=false, node, index) if 'item' does not exist, but should be inserted at index in node.keys
-func (b *BTree[T]) searchNode(node *bNode[T], target T) (bool, *bNode[T], int) {
-	if node == nil {
-		return false, nil, 0
-	}
+// Get returns the value stored under 'key' and whether the key exists
+func (b *BTree[K, V]) Get(key K) (V, bool) {
+	var zero V
+	b.mu.RLock()
+	defer b.mu.RUnlock()
 
-	for i, key := range node.keys {
-		if key == target {
-			// Found exact match
-			return true, node, i
-		}
-		if target < key {
-			// Should go to the left child if not leaf
-			if node.leaf {
-				return false, node, i
-			}
-			return b.searchNode(node.children[i], target)
-		}
+	leaf := b.readLeaf(func(n *bNode[K, V]) int { return b.childIndex(n, key) })
+	if leaf == nil {
+		return zero, false
 	}
-	// If target > all keys in node
-	if node.leaf {
-		return false, node, len(node.keys)
+	defer leaf.latch.RUnlock()
+
+	found, index := b.leafIndex(leaf, key)
+	if !found {
+		return zero, false
 	}
-	return b.searchNode(node.children[len(node.children)-1], target)
+	return leaf.values[index], true
 }
 
-// Insert adds a new 'item' to the B+ Tree
-func (b *BTree[T]) Insert(item T) {
-	// If tree is uninitialized (edge case)
-	if b.root == nil {
-		b.root = newBNode[T]()
-		b.root.keys = append(b.root.keys, item)
-		return
+// childIndex returns which child of internal node 'n' 'target' routes to.
+// Separator keys[i] is the smallest key of children[i+1], so equal keys route to the right
+func (b *BTree[K, V]) childIndex(n *bNode[K, V], target K) int {
+	for i, key := range n.keys {
+		if b.compare(target, key) < 0 {
+			return i
+		}
 	}
+	return len(n.keys)
+}
 
-	// 1. Find the correct position (leaf node + index)
-	exist, node, index := b.searchNode(b.root, item)
-	if exist {
-		// No duplicates
-		return
+// leafIndex returns:
+//
+//	(found=true, index) if 'target' exists at n.keys[index]
+//	(found=false, index) if 'target' does not exist, but should be inserted at index in n.keys
+func (b *BTree[K, V]) leafIndex(n *bNode[K, V], target K) (bool, int) {
+	return searchKeys(n.keys, target, b.compare)
+}
+
+// searchKeys is leafIndex over a plain sorted slice
+func searchKeys[K any](keys []K, target K, compare func(a, b K) int) (bool, int) {
+	for i, key := range keys {
+		switch c := compare(target, key); {
+		case c == 0:
+			// Found exact match
+			return true, i
+		case c < 0:
+			return false, i
+		}
 	}
-	if node == nil {
-		panic("searchNode returned nil node on Insert")
+	// If target > all keys
+	return false, len(keys)
+}
+
+// Insert adds a new 'item' to the B+ Tree with a zero value.
+// Existing keys are left untouched
+func (b *BTree[K, V]) Insert(item K) {
+	var zero V
+	b.insert(item, zero, false)
+}
+
+// InsertUnique adds 'item' with a zero value like Insert, but reports an
+// existing key as ErrDuplicateKey instead of ignoring it
+func (b *BTree[K, V]) InsertUnique(item K) error {
+	var zero V
+	if _, exists := b.insert(item, zero, false); exists {
+		return fmt.Errorf("%w: %v", ErrDuplicateKey, item)
 	}
-	// 2. Insert the key in the leaf at 'index'
-	node.keys = append(node.keys[:index], append([]T{item}, node.keys[index:]...)...)
+	return nil
+}
+
+// Put stores 'value' under 'key', overwriting the value if the key already exists
+func (b *BTree[K, V]) Put(key K, value V) {
+	b.insert(key, value, true)
+}
+
+// Upsert is Put that also returns the value it replaced, and whether there was one
+func (b *BTree[K, V]) Upsert(key K, value V) (old V, replaced bool) {
+	return b.insert(key, value, true)
+}
+
+// insert places (key, value) in the correct leaf, splitting as needed.
+// If the key exists its value is only replaced when 'overwrite' is set.
+// It returns the value the key held before and whether it existed
+func (b *BTree[K, V]) insert(key K, value V, overwrite bool) (old V, exist bool) {
+	// 1. Latch the path down to the leaf that owns 'key'
+	b.withPath(key, crabInsert, func(exists bool) bool { return !exists }, func(path *latchPath[K, V]) {
+		// If tree is uninitialized (edge case), the root latch is still held
+		if path.leaf() == nil {
+			b.initRoot(key, value)
+			return
+		}
+
+		// 2. Find the correct position in the leaf
+		node := path.leaf()
+		var index int
+		exist, index = b.leafIndex(node, key)
+		if exist {
+			// No duplicates
+			old = node.values[index]
+			if overwrite {
+				node.values[index] = value
+			}
+			return
+		}
+		// 3. Insert the key and its value in the leaf at 'index'
+		path.adjustCounts(1)
+		b.insertAt(node, index, key, value)
+	})
+	return old, exist
+}
 
-	// 3. Check for overfill => split
+// initRoot gives a tree without a root its first entry, the caller holds the root latch
+func (b *BTree[K, V]) initRoot(key K, value V) {
+	b.root = b.newNode(true)
+	b.root.keys = append(b.root.keys, key)
+	b.root.values = append(b.root.values, value)
+}
+
+// insertAt puts (key, value) at 'index' of a latched leaf
+func (b *BTree[K, V]) insertAt(node *bNode[K, V], index int, key K, value V) {
+	node.keys = append(node.keys[:index], append([]K{key}, node.keys[index:]...)...)
+	node.values = append(node.values[:index], append([]V{value}, node.values[index:]...)...)
+	node.version++
+
+	// Check for overfill => split, every node the split can reach is still latched
 	if node.overFill(b.degree) {
 		b.Split(node)
 	}
 }
 
-// Delete removes 'item' from the tree, returning true if found/deleted
-// Public Delete: wraps our internal recursive method.
-func (b *BTree[T]) Delete(item T) bool {
-	if b.root == nil {
-		return false // Empty tree
+// Delete removes 'key' from the tree, returning its value and true if found/deleted
+// Public Delete: wraps our internal method.
+func (b *BTree[K, V]) Delete(key K) (value V, found bool) {
+	b.withPath(key, crabDelete, func(exists bool) bool { return exists }, func(path *latchPath[K, V]) {
+		if path.leaf() == nil {
+			return // Empty tree
+		}
+
+		// Keys only live in leaves so internal separators are left as they are,
+		// a stale separator still routes correctly since it bounds both of its children
+		var i int
+		if found, i = b.leafIndex(path.leaf(), key); !found {
+			return
+		}
+		// If the root becomes empty while merging, the tree height shrinks there
+		path.adjustCounts(-1)
+		value = b.removeAt(path.leaf(), i)
+	})
+	return value, found
+}
+
+// removeAt drops the entry at 'i' of a latched leaf and returns its value
+func (b *BTree[K, V]) removeAt(leaf *bNode[K, V], i int) V {
+	value := leaf.values[i]
+	leaf.keys = append(leaf.keys[:i], leaf.keys[i+1:]...)
+	leaf.values = append(leaf.values[:i], leaf.values[i+1:]...)
+	leaf.version++
+
+	// After removal, check if leaf underflows. An underfilled leaf was unsafe
+	// while crabbing, so its parent is latched and 'parent' can be read
+	if leaf.underFill(b.degree) && leaf.parent != nil {
+		b.rebalance(leaf)
 	}
+	return value
+}
 
-	deleted := b.deleteKey(b.root, item)
-	if !deleted {
-		return false // Key not found
+// rebalance fixes an underfilled node by borrowing a key from a sibling
+// that has one to spare, and only merges when neither sibling can lend.
+// The caller holds 'node' and its parent latched, siblings are latched here
+func (b *BTree[K, V]) rebalance(node *bNode[K, V]) {
+	parent := node.parent
+	if parent == nil {
+		// If node is root, nothing to borrow from
+		return
 	}
 
-	// If the root became empty and has children, shrink the tree height
-	if len(b.root.keys) == 0 && len(b.root.children) > 0 {
-		b.root = b.root.children[0]
-		b.root.parent = nil
+	// Siblings may change below, so ones shared with a Snapshot are copied first
+	nodeIndex := findChildIndex(parent, node)
+	if nodeIndex > 0 {
+		left := parent.children[nodeIndex-1]
+		left.latch.Lock()
+		left = b.own(left, parent, node)
+		defer left.latch.Unlock()
+		if left.canLend(b.degree) {
+			borrowFromLeft(node, left, parent, nodeIndex-1)
+			return
+		}
+	}
+	if nodeIndex < len(parent.children)-1 {
+		right := parent.children[nodeIndex+1]
+		right.latch.Lock()
+		right = b.own(right, parent, node)
+		defer right.latch.Unlock()
+		if right.canLend(b.degree) {
+			borrowFromRight(node, right, parent, nodeIndex)
+			return
+		}
 	}
-	return true
+	b.Merge(node)
 }
 
-// Recursively delete 'item' from 'node' or its children.
-func (b *BTree[T]) deleteKey(node *bNode[T], item T) bool {
-	// 1. Find the position 'i' of 'item' or where 'item' should be in node.keys
-	i := 0
-	for i < len(node.keys) && node.keys[i] < item {
-		i++
+// borrowFromLeft moves the last entry of 'left' to the front of 'node'
+// and updates the separator between them at parent.keys[parentKeyIndex]
+func borrowFromLeft[K any, V any](node, left, parent *bNode[K, V], parentKeyIndex int) {
+	last := len(left.keys) - 1
+	if node.leaf {
+		node.keys = append([]K{left.keys[last]}, node.keys...)
+		node.values = append([]V{left.values[last]}, node.values...)
+		left.keys = left.keys[:last]
+		left.values = left.values[:last]
+		node.version++
+		left.version++
+		if parent.counts != nil {
+			parent.counts[parentKeyIndex]--
+			parent.counts[parentKeyIndex+1]++
+		}
+		// The separator is the smallest key of the right node
+		parent.keys[parentKeyIndex] = node.keys[0]
+		return
 	}
 
-	// 2. If 'item' found in node.keys[i]
-	if i < len(node.keys) && node.keys[i] == item {
-		// Case A: node is a LEAF
-		if node.leaf {
-			// Remove the key from the leaf
-			node.keys = append(node.keys[:i], node.keys[i+1:]...)
-		} else {
-			// Case B: node is INTERNAL
-			// We must swap 'item' with the predecessor or successor key
-			// and then delete that key from the child leaf.
-
-			// We'll pick the predecessor for demonstration:
-			predNode := b.getPredecessorNode(node.children[i]) // rightmost leaf in left subtree
-			predKey := predNode.keys[len(predNode.keys)-1]
-
-			// Swap
-			node.keys[i] = predKey
-			// Recursively delete 'predKey' from predNode (which is a leaf)
-			b.deleteKey(predNode, predKey)
-		}
-	} else {
-		// Not found in this node
-		// If leaf, it's not in the tree
-		if node.leaf {
-			return false
+	// Internal: rotate through the parent, the separator comes down
+	// and the last key of 'left' goes up in its place
+	child := left.children[len(left.children)-1]
+	node.keys = append([]K{parent.keys[parentKeyIndex]}, node.keys...)
+	node.children = append([]*bNode[K, V]{child}, node.children...)
+	child.parent = node
+	parent.keys[parentKeyIndex] = left.keys[last]
+	left.keys = left.keys[:last]
+	left.children = left.children[:len(left.children)-1]
+	if parent.counts != nil {
+		count := left.counts[len(left.counts)-1]
+		node.counts = append([]int{count}, node.counts...)
+		parent.counts[parentKeyIndex] -= count
+		parent.counts[parentKeyIndex+1] += count
+		left.counts = left.counts[:len(left.counts)-1]
+	}
+}
+
+// borrowFromRight moves the first entry of 'right' to the end of 'node'
+// and updates the separator between them at parent.keys[parentKeyIndex]
+func borrowFromRight[K any, V any](node, right, parent *bNode[K, V], parentKeyIndex int) {
+	if node.leaf {
+		node.keys = append(node.keys, right.keys[0])
+		node.values = append(node.values, right.values[0])
+		right.keys = append([]K{}, right.keys[1:]...)
+		right.values = append([]V{}, right.values[1:]...)
+		node.version++
+		right.version++
+		if parent.counts != nil {
+			parent.counts[parentKeyIndex]++
+			parent.counts[parentKeyIndex+1]--
 		}
-		// Otherwise, descend into children[i]
-		b.deleteKey(node.children[i], item)
+		parent.keys[parentKeyIndex] = right.keys[0]
+		return
 	}
 
-	// After removal, check if node underflows
-	if node != nil && node != b.root && node.underFill(b.degree) {
-		b.Merge(node)
+	// Internal: rotate through the parent
+	child := right.children[0]
+	node.keys = append(node.keys, parent.keys[parentKeyIndex])
+	node.children = append(node.children, child)
+	child.parent = node
+	parent.keys[parentKeyIndex] = right.keys[0]
+	right.keys = append([]K{}, right.keys[1:]...)
+	right.children = append([]*bNode[K, V]{}, right.children[1:]...)
+	if parent.counts != nil {
+		count := right.counts[0]
+		node.counts = append(node.counts, count)
+		parent.counts[parentKeyIndex] += count
+		parent.counts[parentKeyIndex+1] -= count
+		right.counts = append([]int{}, right.counts[1:]...)
 	}
-	return true
 }
 
-// getPredecessorNode traverses to the rightmost leaf in 'curNode'
-func (b *BTree[T]) getPredecessorNode(curNode *bNode[T]) *bNode[T] {
-	// We go down curNode's last child pointer repeatedly
-	// until we reach a leaf. That leaf's last key is the predecessor.
-	for !curNode.leaf {
-		curNode = curNode.children[len(curNode.children)-1]
+// size returns the number of keys stored below 'n'. Without counts that
+// means visiting every node below it
+func (n *bNode[K, V]) size() int {
+	if n.leaf {
+		return len(n.keys)
 	}
-	return curNode
+	total := 0
+	if n.counts == nil {
+		for _, child := range n.children {
+			total += child.size()
+		}
+		return total
+	}
+	for _, count := range n.counts {
+		total += count
+	}
+	return total
 }
 
 // overFill checks if node has >= 'degree' keys
-func (n *bNode[T]) overFill(degree uint16) bool {
+func (n *bNode[K, V]) overFill(degree uint16) bool {
 	return len(n.keys) >= int(degree)
 }
 
-// underFill checks if node has fewer than floor(degree/2) keys
-// e.g., for degree=4, node must have >=2 keys. So if <2 => underfill
-func (n *bNode[T]) underFill(degree uint16) bool {
-	minKeys := int(math.Ceil(float64(degree)/2.0)) - 1
-	// e.g., degree=4 => minKeys=1
-	return len(n.keys) < minKeys
+// minKeys is the fewest keys a non-root node may hold
+// e.g., degree=4 => minKeys=1
+func minKeys(degree uint16) int {
+	return int(math.Ceil(float64(degree)/2.0)) - 1
+}
+
+// underFill checks if node has fewer than ceil(degree/2)-1 keys
+func (n *bNode[K, V]) underFill(degree uint16) bool {
+	return len(n.keys) < minKeys(degree)
+}
+
+// canLend checks if node can give up a key and still not be underfilled
+func (n *bNode[K, V]) canLend(degree uint16) bool {
+	return len(n.keys) > minKeys(degree)
 }
 
 // Split handles overfilled nodes
-func (b *BTree[T]) Split(node *bNode[T]) {
+func (b *BTree[K, V]) Split(node *bNode[K, V]) {
+	b.splits.Add(1)
 	mid := len(node.keys) / 2
-	middleKey := node.keys[mid]
 
-	sibling := &bNode[T]{
-		keys:   append([]T{}, node.keys[mid+1:]...),
-		leaf:   node.leaf,
-		parent: node.parent,
-	}
-
-	// Left node keeps left half
-	node.keys = node.keys[:mid]
+	sibling := b.newNode(node.leaf)
+	sibling.parent = node.parent
 
-	if !node.leaf {
-		// Split children for internal node
-		sibling.children = append([]*bNode[T]{}, node.children[mid+1:]...)
+	var middleKey K
+	if node.leaf {
+		// Leaf: the right half keeps every key and value, a copy of
+		// its first key goes up as the separator
+		middleKey = node.keys[mid]
+		sibling.keys = append([]K{}, node.keys[mid:]...)
+		sibling.values = append([]V{}, node.values[mid:]...)
+		node.keys = node.keys[:mid]
+		node.values = node.values[:mid]
+		node.version++
+
+		// Fix 'next' and 'prev' pointers, the right neighbour may belong
+		// to another parent so it is latched before being touched
+		sibling.next = node.next
+		sibling.prev = node
+		if node.next != nil {
+			node.next.latch.Lock()
+			node.next.prev = sibling
+			node.next.version++
+			node.next.latch.Unlock()
+		}
+		node.next = sibling
+	} else {
+		// Internal: the middle key moves up and is not kept in either half
+		middleKey = node.keys[mid]
+		sibling.keys = append([]K{}, node.keys[mid+1:]...)
+		sibling.children = append([]*bNode[K, V]{}, node.children[mid+1:]...)
+		node.keys = node.keys[:mid]
 		node.children = node.children[:mid+1]
+		if b.counted {
+			sibling.counts = append([]int{}, node.counts[mid+1:]...)
+			node.counts = node.counts[:mid+1]
+		}
 
 		// Reassign parents
 		for _, child := range sibling.children {
 			child.parent = sibling
 		}
-	} else {
-		// Leaf node: fix 'next' pointer
-		sibling.next = node.next
-		node.next = sibling
 	}
 
 	if node.parent == nil {
 		// Splitting root
-		newRoot := &bNode[T]{
-			keys:     []T{middleKey},
-			leaf:     false,
-			children: []*bNode[T]{node, sibling},
+		newRoot := b.newNode(false)
+		newRoot.keys = []K{middleKey}
+		newRoot.children = []*bNode[K, V]{node, sibling}
+		if b.counted {
+			newRoot.counts = []int{node.size(), sibling.size()}
 		}
 		node.parent = newRoot
 		sibling.parent = newRoot
 		b.root = newRoot
 	} else {
-		// Insert 'middleKey' into parent
+		// Insert 'middleKey' into parent, right after 'node'
 		parent := node.parent
 
-		insertPos := 0
-		for insertPos < len(parent.keys) && parent.keys[insertPos] < middleKey {
-			insertPos++
-		}
+		insertPos := findChildIndex(parent, node)
 		parent.keys = append(parent.keys[:insertPos],
-			append([]T{middleKey}, parent.keys[insertPos:]...)...,
+			append([]K{middleKey}, parent.keys[insertPos:]...)...,
 		)
 
 		parent.children = append(
 			parent.children[:insertPos+1],
-			append([]*bNode[T]{sibling}, parent.children[insertPos+1:]...)...,
+			append([]*bNode[K, V]{sibling}, parent.children[insertPos+1:]...)...,
 		)
 
+		// The keys 'node' had are now shared between it and 'sibling'
+		if b.counted {
+			parent.counts[insertPos] = node.size()
+			parent.counts = append(
+				parent.counts[:insertPos+1],
+				append([]int{sibling.size()}, parent.counts[insertPos+1:]...)...,
+			)
+		}
+
 		if parent.overFill(b.degree) {
 			b.Split(parent)
 		}
 	}
 }
 
-// Merge handles underfilled nodes
-func (b *BTree[T]) Merge(node *bNode[T]) {
+// Merge handles underfilled nodes whose siblings have no keys to spare.
+// The caller holds the latches of 'node', its parent and the sibling it merges with
+func (b *BTree[K, V]) Merge(node *bNode[K, V]) {
 	parent := node.parent
 	if parent == nil {
 		// If node is root, no merge needed
@@ -284,18 +551,20 @@ func (b *BTree[T]) Merge(node *bNode[T]) {
 	}
 
 	nodeIndex := findChildIndex(parent, node)
-	// If we can merge with left sibling, do so; else merge with right sibling
-	if nodeIndex > 0 {
-		leftSibling := parent.children[nodeIndex-1]
-		mergeRightIntoLeft(leftSibling, node, parent, nodeIndex-1, b)
-	} else {
+	// If we can merge with right sibling, do so; else merge with left sibling.
+	// Going right first means the leaf after the merged pair is never one
+	// rebalance already latched
+	if nodeIndex < len(parent.children)-1 {
 		rightSibling := parent.children[nodeIndex+1]
 		mergeRightIntoLeft(node, rightSibling, parent, nodeIndex, b)
+	} else {
+		leftSibling := parent.children[nodeIndex-1]
+		mergeRightIntoLeft(leftSibling, node, parent, nodeIndex-1, b)
 	}
 }
 
 // findChildIndex locates 'child' in 'parent.children'
-func findChildIndex[T Ordered](parent *bNode[T], child *bNode[T]) int {
+func findChildIndex[K any, V any](parent *bNode[K, V], child *bNode[K, V]) int {
 	for i, c := range parent.children {
 		if c == child {
 			return i
@@ -306,32 +575,63 @@ func findChildIndex[T Ordered](parent *bNode[T], child *bNode[T]) int {
 
 // mergeRightIntoLeft merges 'right' node into 'left' node
 // then removes 'right' from the parent
-func mergeRightIntoLeft[T Ordered](
-	left *bNode[T],
-	right *bNode[T],
-	parent *bNode[T],
+func mergeRightIntoLeft[K any, V any](
+	left *bNode[K, V],
+	right *bNode[K, V],
+	parent *bNode[K, V],
 	parentKeyIndex int,
-	b *BTree[T],
+	b *BTree[K, V],
 ) {
+	b.merges.Add(1)
+	joinSiblings(left, right, parent, parentKeyIndex)
+
+	// Check if parent is underfilled. Only the root or a parent that was unsafe
+	// while crabbing gets inside, so reading parent.parent is covered by a held latch
+	if len(parent.keys) == 0 || parent.underFill(b.degree) {
+		if parent.parent == nil {
+			if len(parent.keys) == 0 {
+				// If parent is root and empty => shrink
+				b.root = parent.children[0]
+				b.root.parent = nil
+			}
+		} else {
+			b.rebalance(parent)
+		}
+	}
+}
+
+// joinSiblings moves everything in 'right' into 'left' and removes 'right'
+// and the separator at parent.keys[parentKeyIndex] from the parent
+func joinSiblings[K any, V any](left, right, parent *bNode[K, V], parentKeyIndex int) {
 	// Key in parent that separates left and right
 	separatingKey := parent.keys[parentKeyIndex]
 
-	// If not leaf, copy separatingKey into left
 	if !left.leaf {
+		// If not leaf, pull separatingKey down into left
 		left.keys = append(left.keys, separatingKey)
-	}
-	// Merge right.keys into left
-	left.keys = append(left.keys, right.keys...)
+		left.keys = append(left.keys, right.keys...)
 
-	if !left.leaf {
 		// Merge children
 		left.children = append(left.children, right.children...)
+		left.counts = append(left.counts, right.counts...)
 		for _, child := range right.children {
 			child.parent = left
 		}
 	} else {
-		// If leaf, fix the leaf chain
+		// Leaves already hold every key, the separator is simply dropped
+		left.keys = append(left.keys, right.keys...)
+		left.values = append(left.values, right.values...)
+		left.version++
+		right.version++
+
+		// If leaf, fix the leaf chain in both directions
 		left.next = right.next
+		if right.next != nil {
+			right.next.latch.Lock()
+			right.next.prev = left
+			right.next.version++
+			right.next.latch.Unlock()
+		}
 	}
 
 	// Remove 'separatingKey' from parent
@@ -340,32 +640,31 @@ func mergeRightIntoLeft[T Ordered](
 		parent.keys[parentKeyIndex+1:]...,
 	)
 
-	// Remove 'right' pointer from parent.children
+	// Remove 'right' pointer from parent.children, 'left' now counts its keys
 	rightIndex := findChildIndex(parent, right)
 	parent.children = append(
 		parent.children[:rightIndex],
 		parent.children[rightIndex+1:]...,
 	)
-
-	// Check if parent is underfilled
-	if parent.underFill(b.degree) {
-		if parent == b.root && len(parent.keys) == 0 {
-			// If parent is root and empty => shrink
-			b.root = parent.children[0]
-			b.root.parent = nil
-		} else {
-			b.Merge(parent)
-		}
+	if parent.counts != nil {
+		parent.counts[rightIndex-1] += parent.counts[rightIndex]
+		parent.counts = append(
+			parent.counts[:rightIndex],
+			parent.counts[rightIndex+1:]...,
+		)
 	}
 }
 
 // Display prints the tree in a level-order (BFS) format
-func (b *BTree[T]) Display() {
+func (b *BTree[K, V]) Display() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	if b.root == nil {
 		fmt.Println("Empty tree. No levels to print.")
 		return
 	}
-	queue := NewQueue[*bNode[T]]()
+	queue := NewQueue[*bNode[K, V]]()
 	queue.Enqueue(b.root)
 	queue.Enqueue(nil)
 
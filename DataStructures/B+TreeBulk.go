@@ -0,0 +1,191 @@
+package DataStructures
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	ErrUnsortedInput  = errors.New("btree: bulk load input is not sorted in strictly increasing key order")
+	ErrTreeNotEmpty   = errors.New("btree: bulk load needs an empty tree")
+	ErrBadFillFactor  = errors.New("btree: fill factor must be in (0, 1]")
+	ErrLengthMismatch = errors.New("btree: keys and values have different lengths")
+)
+
+// sliceIterator walks a pair of parallel key/value slices
+type sliceIterator[K any, V any] struct {
+	keys   []K
+	values []V
+	index  int
+}
+
+// NewSliceIterator returns an Iterator over keys[i] -> values[i].
+// 'values' may be nil, in which case every value is the zero value
+func NewSliceIterator[K any, V any](keys []K, values []V) (Iterator[K, V], error) {
+	if values != nil && len(values) != len(keys) {
+		return nil, ErrLengthMismatch
+	}
+	return &sliceIterator[K, V]{keys: keys, values: values}, nil
+}
+
+func (s *sliceIterator[K, V]) Valid() bool { return s.index >= 0 && s.index < len(s.keys) }
+func (s *sliceIterator[K, V]) Next()       { s.index++ }
+func (s *sliceIterator[K, V]) Prev()       { s.index-- }
+func (s *sliceIterator[K, V]) Key() K      { return s.keys[s.index] }
+func (s *sliceIterator[K, V]) Value() V {
+	if s.values == nil {
+		var zero V
+		return zero
+	}
+	return s.values[s.index]
+}
+
+// BulkLoad builds the tree bottom-up from 'entries', which must yield keys in
+// strictly increasing order. Leaves are packed left to right to 'fillFactor' of
+// their capacity, then every internal level is built on top of the one below.
+// The tree must be empty, and is left untouched if an error is returned
+func (b *BTree[K, V]) BulkLoad(entries Iterator[K, V], fillFactor float64) error {
+	if !(fillFactor > 0 && fillFactor <= 1) {
+		return ErrBadFillFactor
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.root != nil && (len(b.root.keys) > 0 || !b.root.leaf) {
+		return ErrTreeNotEmpty
+	}
+
+	// 1. Pack leaves
+	leafTarget := bulkTarget(fillFactor, int(b.degree)-1, minKeys(b.degree))
+	var leaves []*bNode[K, V]
+	var last K
+	for ; entries.Valid(); entries.Next() {
+		key := entries.Key()
+		if len(leaves) > 0 && b.compare(last, key) >= 0 {
+			return fmt.Errorf("%w: %v follows %v", ErrUnsortedInput, key, last)
+		}
+		if len(leaves) == 0 || len(leaves[len(leaves)-1].keys) == leafTarget {
+			leaves = append(leaves, b.newNode(true))
+		}
+		leaf := leaves[len(leaves)-1]
+		leaf.keys = append(leaf.keys, key)
+		leaf.values = append(leaf.values, entries.Value())
+		last = key
+	}
+
+	if len(leaves) == 0 {
+		b.root = b.newNode(true)
+		return nil
+	}
+	leaves = b.balanceLastLeaves(leaves)
+
+	// 2. Link the leaf chain
+	for i := 1; i < len(leaves); i++ {
+		leaves[i-1].next = leaves[i]
+		leaves[i].prev = leaves[i-1]
+	}
+
+	// 3. Build internal levels until a single node is left
+	level := leaves
+	childTarget := bulkTarget(fillFactor, int(b.degree), minKeys(b.degree)+1)
+	if childTarget < 2 {
+		childTarget = 2
+	}
+	for len(level) > 1 {
+		level = b.buildParentLevel(level, childTarget)
+	}
+
+	b.root = level[0]
+	b.root.parent = nil
+	return nil
+}
+
+// bulkTarget is how many entries to pack in a node: 'fillFactor' of 'capacity',
+// but never less than 'minimum' so nodes are not born underfilled
+func bulkTarget(fillFactor float64, capacity, minimum int) int {
+	target := int(math.Round(fillFactor * float64(capacity)))
+	if target < minimum {
+		target = minimum
+	}
+	if target < 1 {
+		target = 1
+	}
+	if target > capacity {
+		target = capacity
+	}
+	return target
+}
+
+// balanceLastLeaves fixes an underfilled last leaf by sharing keys with the
+// one before it, or folding both into one leaf when they fit
+func (b *BTree[K, V]) balanceLastLeaves(leaves []*bNode[K, V]) []*bNode[K, V] {
+	n := len(leaves)
+	if n < 2 || !leaves[n-1].underFill(b.degree) {
+		return leaves
+	}
+	left, right := leaves[n-2], leaves[n-1]
+	keys := append(append([]K{}, left.keys...), right.keys...)
+	values := append(append([]V{}, left.values...), right.values...)
+	if len(keys) < int(b.degree) {
+		left.keys, left.values = keys, values
+		return leaves[:n-1]
+	}
+	mid := len(keys) / 2
+	left.keys, left.values = keys[:mid], values[:mid]
+	right.keys, right.values = keys[mid:], values[mid:]
+	return leaves
+}
+
+// buildParentLevel groups 'children' under new internal nodes of about
+// 'target' children each and returns the new level
+func (b *BTree[K, V]) buildParentLevel(children []*bNode[K, V], target int) []*bNode[K, V] {
+	var groups [][]*bNode[K, V]
+	for start := 0; start < len(children); start += target {
+		end := start + target
+		if end > len(children) {
+			end = len(children)
+		}
+		groups = append(groups, children[start:end])
+	}
+
+	// Same fix up as the leaves, an internal node needs minKeys+1 children
+	if n := len(groups); n > 1 && len(groups[n-1]) < minKeys(b.degree)+1 {
+		merged := append(append([]*bNode[K, V]{}, groups[n-2]...), groups[n-1]...)
+		if len(merged) <= int(b.degree) {
+			groups = append(groups[:n-2], merged)
+		} else {
+			mid := len(merged) / 2
+			groups[n-2], groups[n-1] = merged[:mid], merged[mid:]
+		}
+	}
+
+	parents := make([]*bNode[K, V], 0, len(groups))
+	for _, group := range groups {
+		parent := b.newNode(false)
+		parent.keys = make([]K, 0, len(group)-1)
+		parent.children = append([]*bNode[K, V]{}, group...)
+		if b.counted {
+			parent.counts = make([]int, 0, len(group))
+		}
+		for i, child := range group {
+			child.parent = parent
+			if b.counted {
+				parent.counts = append(parent.counts, child.size())
+			}
+			if i > 0 {
+				// Separator is the smallest key below the right child
+				parent.keys = append(parent.keys, subtreeMin(child))
+			}
+		}
+		parents = append(parents, parent)
+	}
+	return parents
+}
+
+// subtreeMin returns the smallest key stored below 'node'
+func subtreeMin[K any, V any](node *bNode[K, V]) K {
+	for !node.leaf {
+		node = node.children[0]
+	}
+	return node.keys[0]
+}
@@ -0,0 +1,136 @@
+package DataStructures
+
+import (
+	"errors"
+	"testing"
+)
+
+func mustSliceIterator[V any](t *testing.T, keys []int, values []V) Iterator[int, V] {
+	t.Helper()
+	it, err := NewSliceIterator(keys, values)
+	if err != nil {
+		t.Fatalf("NewSliceIterator: %v", err)
+	}
+	return it
+}
+
+func TestBulkLoad(t *testing.T) {
+	for _, degree := range []uint16{3, 4, 5, 8, 16} {
+		for _, fill := range []float64{0.1, 0.5, 0.7, 1} {
+			for _, n := range []int{0, 1, 2, 3, 7, 10, 33, 100, 1000} {
+				keys := make([]int, n)
+				values := make([]int, n)
+				for i := range keys {
+					keys[i] = i * 3
+					values[i] = i
+				}
+
+				btree := newBTree[int, int](degree)
+				if err := btree.BulkLoad(mustSliceIterator(t, keys, values), fill); err != nil {
+					t.Fatalf("degree %d fill %.1f n %d: BulkLoad: %v", degree, fill, n, err)
+				}
+				if err := btree.Validate(); err != nil {
+					t.Fatalf("degree %d fill %.1f n %d: %v", degree, fill, n, err)
+				}
+				if got := leafKeys(btree); !equalKeys(got, keys) {
+					t.Fatalf("degree %d fill %.1f n %d: leaf chain holds %v", degree, fill, n, got)
+				}
+				for i, k := range keys {
+					if v, ok := btree.Get(k); !ok || v != values[i] {
+						t.Fatalf("degree %d fill %.1f n %d: Get(%d) = (%d, %v)", degree, fill, n, k, v, ok)
+					}
+				}
+
+				// The loaded tree must keep working for regular operations
+				btree.Put(1, -1)
+				btree.Delete(0)
+				if err := btree.Validate(); err != nil {
+					t.Fatalf("degree %d fill %.1f n %d: after updates: %v", degree, fill, n, err)
+				}
+			}
+		}
+	}
+}
+
+func TestBulkLoadFillFactor(t *testing.T) {
+	keys := make([]int, 1000)
+	for i := range keys {
+		keys[i] = i
+	}
+
+	full := newBTree[int, int](11)
+	full.BulkLoad(mustSliceIterator[int](t, keys, nil), 1)
+	half := newBTree[int, int](11)
+	half.BulkLoad(mustSliceIterator[int](t, keys, nil), 0.5)
+
+	// Full leaves hold degree-1 keys, half full ones half of that
+	if got := len(full.leftmostLeaf().keys); got != 10 {
+		t.Errorf("Fill factor 1 expected 10 keys per leaf, got %d", got)
+	}
+	if got := len(half.leftmostLeaf().keys); got != 5 {
+		t.Errorf("Fill factor 0.5 expected 5 keys per leaf, got %d", got)
+	}
+}
+
+func TestBulkLoadRejectsBadInput(t *testing.T) {
+	t.Run("unsorted", func(t *testing.T) {
+		btree := newBTree[int, int](4)
+		err := btree.BulkLoad(mustSliceIterator[int](t, []int{1, 2, 5, 4}, nil), 1)
+		if !errors.Is(err, ErrUnsortedInput) {
+			t.Errorf("Expected ErrUnsortedInput, got %v", err)
+		}
+		if btree.Search(1) {
+			t.Errorf("A failed bulk load must leave the tree untouched")
+		}
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		btree := newBTree[int, int](4)
+		err := btree.BulkLoad(mustSliceIterator[int](t, []int{1, 2, 2, 3}, nil), 1)
+		if !errors.Is(err, ErrUnsortedInput) {
+			t.Errorf("Expected ErrUnsortedInput for duplicate keys, got %v", err)
+		}
+	})
+
+	t.Run("non-empty tree", func(t *testing.T) {
+		btree := newBTree[int, int](4)
+		btree.Insert(1)
+		err := btree.BulkLoad(mustSliceIterator[int](t, []int{2, 3}, nil), 1)
+		if !errors.Is(err, ErrTreeNotEmpty) {
+			t.Errorf("Expected ErrTreeNotEmpty, got %v", err)
+		}
+	})
+
+	t.Run("fill factor", func(t *testing.T) {
+		for _, fill := range []float64{0, -0.5, 1.5} {
+			err := newBTree[int, int](4).BulkLoad(mustSliceIterator[int](t, []int{1}, nil), fill)
+			if !errors.Is(err, ErrBadFillFactor) {
+				t.Errorf("Fill factor %v: expected ErrBadFillFactor, got %v", fill, err)
+			}
+		}
+	})
+
+	t.Run("mismatched slices", func(t *testing.T) {
+		if _, err := NewSliceIterator([]int{1, 2}, []int{1}); !errors.Is(err, ErrLengthMismatch) {
+			t.Errorf("Expected ErrLengthMismatch, got %v", err)
+		}
+	})
+}
+
+func TestBulkLoadFromCursor(t *testing.T) {
+	// Restoring one tree from another's ordered scan
+	source := newBTree[int, string](5)
+	for i := 0; i < 200; i++ {
+		source.Put(i*7%211, "v")
+	}
+	copied := newBTree[int, string](4)
+	if err := copied.BulkLoad(source.First(), 0.8); err != nil {
+		t.Fatalf("BulkLoad from cursor: %v", err)
+	}
+	if err := copied.Validate(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if !equalKeys(leafKeys(copied), leafKeys(source)) {
+		t.Errorf("Copied tree differs from its source")
+	}
+}
@@ -0,0 +1,306 @@
+package DataStructures
+
+// Iterator walks the entries of a tree in key order, in either direction.
+// Key and Value may only be called while Valid returns true
+type Iterator[K any, V any] interface {
+	Valid() bool
+	Next()
+	Prev()
+	Key() K
+	Value() V
+}
+
+// Cursor is a position in the leaf chain of a BTree.
+// Moving it never touches internal nodes, leaves are followed through 'next' and 'prev'.
+//
+// The cursor works on a copy of one leaf at a time and holds no latch between
+// calls, so it is safe to use while other goroutines modify the tree. Keys come
+// back in order and never twice, and a key present for the whole scan is always
+// returned. A single Cursor must not be shared between goroutines.
+//
+// On a Snapshot the links between leaves belong to the live tree, so the cursor
+// finds neighbouring leaves from the snapshot's root instead, without latches
+type Cursor[K any, V any] struct {
+	tree *BTree[K, V]
+
+	// copy of the current leaf and its neighbours at the time it was read
+	node       *bNode[K, V]
+	version    uint64
+	keys       []K
+	values     []V
+	next, prev *bNode[K, V]
+	stale      bool
+	index      int
+
+	// optional bounds, the cursor becomes invalid once one is passed
+	bounded     bool
+	lo, hi      K
+	loInclusive bool
+	hiInclusive bool
+}
+
+// Valid reports whether the cursor points at an entry
+func (c *Cursor[K, V]) Valid() bool {
+	if c.index < 0 || c.index >= len(c.keys) {
+		return false
+	}
+	if !c.bounded {
+		return true
+	}
+	key := c.keys[c.index]
+	lo, hi := c.tree.compare(key, c.lo), c.tree.compare(key, c.hi)
+	aboveLo := lo > 0 || (c.loInclusive && lo == 0)
+	belowHi := hi < 0 || (c.hiInclusive && hi == 0)
+	return aboveLo && belowHi
+}
+
+// Next moves the cursor to the following entry
+func (c *Cursor[K, V]) Next() {
+	c.index++
+	c.skipExhausted()
+}
+
+// Prev moves the cursor to the preceding entry
+func (c *Cursor[K, V]) Prev() {
+	c.index--
+	c.skipExhaustedBackward()
+}
+
+// Key returns the key under the cursor
+func (c *Cursor[K, V]) Key() K {
+	return c.keys[c.index]
+}
+
+// Value returns the value under the cursor
+func (c *Cursor[K, V]) Value() V {
+	return c.values[c.index]
+}
+
+// load copies 'leaf' into the cursor, the caller holds its read latch
+func (c *Cursor[K, V]) load(leaf *bNode[K, V]) {
+	c.node = leaf
+	c.keys = append([]K{}, leaf.keys...)
+	c.values = append([]V{}, leaf.values...)
+	if c.tree.frozen {
+		c.next, c.prev = c.tree.leafAfter(leaf), c.tree.leafBefore(leaf)
+		return
+	}
+	c.version, c.stale = leaf.version, leaf.stale
+	c.next, c.prev = leaf.next, leaf.prev
+}
+
+// visit latches 'leaf' just long enough to copy it
+func (c *Cursor[K, V]) visit(leaf *bNode[K, V]) {
+	if c.tree.frozen {
+		c.load(leaf)
+		return
+	}
+	c.tree.mu.RLock()
+	leaf.latch.RLock()
+	c.load(leaf)
+	leaf.latch.RUnlock()
+	c.tree.mu.RUnlock()
+}
+
+// changedSince reports whether 'leaf' was modified after the cursor copied it at 'version'
+func (c *Cursor[K, V]) changedSince(leaf *bNode[K, V], version uint64) bool {
+	if c.tree.frozen {
+		return false
+	}
+	c.tree.mu.RLock()
+	leaf.latch.RLock()
+	changed := leaf.version != version
+	leaf.latch.RUnlock()
+	c.tree.mu.RUnlock()
+	return changed
+}
+
+// reseek copies the leaf that owns 'key' by descending from the root again
+func (c *Cursor[K, V]) reseek(key K) {
+	c.tree.mu.RLock()
+	defer c.tree.mu.RUnlock()
+	if leaf := c.tree.readLeaf(func(n *bNode[K, V]) int { return c.tree.childIndex(n, key) }); leaf != nil {
+		c.load(leaf)
+		leaf.latch.RUnlock()
+	}
+}
+
+// skipExhausted moves past the end of the current leaf onto the next one.
+// If the leaf we are leaving changed since it was copied, keys may have moved
+// into it behind the cursor (a split, borrow or merge), so the cursor finds its
+// place again from the root. The same goes for arriving at a leaf that was
+// replaced by a copy. Anything not above the keys already seen is skipped
+func (c *Cursor[K, V]) skipExhausted() {
+	var seen K
+	hasSeen := false
+	for c.index >= len(c.keys) && c.next != nil {
+		if n := len(c.keys); n > 0 && (!hasSeen || c.tree.compare(seen, c.keys[n-1]) < 0) {
+			seen, hasSeen = c.keys[n-1], true
+		}
+		from, version := c.node, c.version
+		c.visit(c.next)
+		if hasSeen && (c.stale || c.changedSince(from, version)) {
+			c.reseek(seen)
+		}
+		c.index = 0
+		for hasSeen && c.index < len(c.keys) && c.tree.compare(seen, c.keys[c.index]) >= 0 {
+			c.index++
+		}
+	}
+	if c.index > len(c.keys) {
+		c.index = len(c.keys)
+	}
+}
+
+// skipExhaustedBackward moves before the start of the current leaf onto the previous one
+func (c *Cursor[K, V]) skipExhaustedBackward() {
+	var seen K
+	hasSeen := false
+	for c.index < 0 && c.prev != nil {
+		if len(c.keys) > 0 && (!hasSeen || c.tree.compare(c.keys[0], seen) < 0) {
+			seen, hasSeen = c.keys[0], true
+		}
+		from, version := c.node, c.version
+		c.visit(c.prev)
+		if hasSeen && (c.stale || c.changedSince(from, version)) {
+			c.reseek(seen)
+		}
+		c.index = len(c.keys) - 1
+		for hasSeen && c.index >= 0 && c.tree.compare(c.keys[c.index], seen) >= 0 {
+			c.index--
+		}
+	}
+	if c.index < -1 {
+		c.index = -1
+	}
+}
+
+// seekLeaf positions a new cursor on a copy of the leaf 'pick' leads to
+func (b *BTree[K, V]) seekLeaf(pick func(*bNode[K, V]) int) *Cursor[K, V] {
+	c := &Cursor[K, V]{tree: b}
+	if b.frozen {
+		if leaf := b.frozenLeaf(pick); leaf != nil {
+			c.load(leaf)
+		}
+		return c
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if leaf := b.readLeaf(pick); leaf != nil {
+		c.load(leaf)
+		leaf.latch.RUnlock()
+	}
+	return c
+}
+
+// Seek returns a cursor on the first entry with a key >= 'key'
+func (b *BTree[K, V]) Seek(key K) Iterator[K, V] {
+	return b.seek(key)
+}
+
+func (b *BTree[K, V]) seek(key K) *Cursor[K, V] {
+	c := b.seekLeaf(func(n *bNode[K, V]) int { return b.childIndex(n, key) })
+	_, c.index = searchKeys(c.keys, key, b.compare)
+	c.skipExhausted()
+	return c
+}
+
+// SeekLast returns a cursor on the last entry with a key <= 'key'
+func (b *BTree[K, V]) SeekLast(key K) Iterator[K, V] {
+	return b.seekLast(key)
+}
+
+func (b *BTree[K, V]) seekLast(key K) *Cursor[K, V] {
+	c := b.seekLeaf(func(n *bNode[K, V]) int { return b.childIndex(n, key) })
+	found, index := searchKeys(c.keys, key, b.compare)
+	if !found {
+		// 'index' is where 'key' would go, the entry before it is the answer
+		index--
+	}
+	c.index = index
+	c.skipExhaustedBackward()
+	return c
+}
+
+// First returns a cursor on the smallest entry, for full ordered scans
+func (b *BTree[K, V]) First() Iterator[K, V] {
+	c := b.seekLeaf(func(*bNode[K, V]) int { return 0 })
+	c.skipExhausted()
+	return c
+}
+
+// Last returns a cursor on the largest entry, for descending scans
+func (b *BTree[K, V]) Last() Iterator[K, V] {
+	c := b.seekLeaf(func(n *bNode[K, V]) int { return len(n.children) - 1 })
+	c.index = len(c.keys) - 1
+	c.skipExhaustedBackward()
+	return c
+}
+
+// Range returns a cursor on the first key between 'lo' and 'hi',
+// each bound is included or excluded according to its flag
+func (b *BTree[K, V]) Range(lo, hi K, loInclusive, hiInclusive bool) Iterator[K, V] {
+	c := b.seek(lo)
+	c.setBounds(lo, hi, loInclusive, hiInclusive)
+	if !loInclusive && c.index < len(c.keys) && b.compare(c.keys[c.index], lo) == 0 {
+		c.Next()
+	}
+	return c
+}
+
+// ReverseRange is Range positioned on the last key between 'lo' and 'hi',
+// walk it with Prev for descending scans
+func (b *BTree[K, V]) ReverseRange(lo, hi K, loInclusive, hiInclusive bool) Iterator[K, V] {
+	c := b.seekLast(hi)
+	c.setBounds(lo, hi, loInclusive, hiInclusive)
+	if !hiInclusive && c.index >= 0 && b.compare(c.keys[c.index], hi) == 0 {
+		c.Prev()
+	}
+	return c
+}
+
+func (c *Cursor[K, V]) setBounds(lo, hi K, loInclusive, hiInclusive bool) {
+	c.bounded = true
+	c.lo, c.hi = lo, hi
+	c.loInclusive, c.hiInclusive = loInclusive, hiInclusive
+}
+
+// Min returns the smallest key in the tree
+func (b *BTree[K, V]) Min() (K, bool) {
+	var zero K
+	c := b.First()
+	if !c.Valid() {
+		return zero, false
+	}
+	return c.Key(), true
+}
+
+// Max returns the largest key in the tree
+func (b *BTree[K, V]) Max() (K, bool) {
+	var zero K
+	c := b.Last()
+	if !c.Valid() {
+		return zero, false
+	}
+	return c.Key(), true
+}
+
+// leftmostLeaf follows the first child pointers down to a leaf.
+// It takes no latches, callers need the tree to themselves
+func (b *BTree[K, V]) leftmostLeaf() *bNode[K, V] {
+	node := b.root
+	for node != nil && !node.leaf {
+		node = node.children[0]
+	}
+	return node
+}
+
+// rightmostLeaf follows the last child pointers down to a leaf.
+// It takes no latches, callers need the tree to themselves
+func (b *BTree[K, V]) rightmostLeaf() *bNode[K, V] {
+	node := b.root
+	for node != nil && !node.leaf {
+		node = node.children[len(node.children)-1]
+	}
+	return node
+}
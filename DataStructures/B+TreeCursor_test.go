@@ -0,0 +1,223 @@
+package DataStructures
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// Compile time check that BTree provides the full DiskTree API
+var _ DiskTree[int, int] = (*BTree[int, int])(nil)
+
+// collectKeys drains an iterator into a slice
+func collectKeys[V any](it Iterator[int, V]) []int {
+	var keys []int
+	for ; it.Valid(); it.Next() {
+		keys = append(keys, it.Key())
+	}
+	return keys
+}
+
+func equalKeys(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCursorSeekAndNext(t *testing.T) {
+	btree := newBTree[int, int](4)
+	for i := 0; i < 100; i += 2 {
+		btree.Put(i, i*10)
+	}
+
+	// Seek on an existing key lands on it
+	c := btree.Seek(40)
+	if !c.Valid() || c.Key() != 40 || c.Value() != 400 {
+		t.Fatalf("Seek(40) expected (40, 400), got valid=%v", c.Valid())
+	}
+	// Seek on a missing key lands on its successor
+	c = btree.Seek(41)
+	if !c.Valid() || c.Key() != 42 {
+		t.Fatalf("Seek(41) expected 42")
+	}
+
+	// Walking from the seek position crosses leaves in order
+	keys := collectKeys(btree.Seek(91))
+	if !equalKeys(keys, []int{92, 94, 96, 98}) {
+		t.Errorf("Scan from 91 got %v", keys)
+	}
+
+	// Seeking past the end gives an invalid cursor
+	if btree.Seek(1000).Valid() {
+		t.Errorf("Seek past the last key should not be valid")
+	}
+
+	if keys := collectKeys(btree.First()); len(keys) != 50 {
+		t.Errorf("Full scan expected 50 keys, got %d", len(keys))
+	}
+}
+
+func TestRange(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	btree := newBTree[int, int](5)
+	present := make(map[int]bool)
+	for i := 0; i < 400; i++ {
+		k := rng.Intn(500)
+		btree.Insert(k)
+		present[k] = true
+	}
+
+	for trial := 0; trial < 200; trial++ {
+		lo, hi := rng.Intn(520)-10, rng.Intn(520)-10
+		loInc, hiInc := rng.Intn(2) == 0, rng.Intn(2) == 0
+
+		var expected []int
+		for k := lo; k <= hi; k++ {
+			if !present[k] || (k == lo && !loInc) || (k == hi && !hiInc) {
+				continue
+			}
+			expected = append(expected, k)
+		}
+
+		got := collectKeys(btree.Range(lo, hi, loInc, hiInc))
+		if !equalKeys(got, expected) {
+			t.Fatalf("Range(%d, %d, %v, %v) = %v, expected %v", lo, hi, loInc, hiInc, got, expected)
+		}
+	}
+}
+
+func TestMinMax(t *testing.T) {
+	btree := newBTree[int, int](3)
+	if _, ok := btree.Min(); ok {
+		t.Errorf("Min on empty tree should report false")
+	}
+	if _, ok := btree.Max(); ok {
+		t.Errorf("Max on empty tree should report false")
+	}
+
+	for _, v := range []int{50, 20, 70, 10, 30, 60, 80, 90, 40} {
+		btree.Insert(v)
+	}
+	if min, ok := btree.Min(); !ok || min != 10 {
+		t.Errorf("Min expected 10, got %d", min)
+	}
+	if max, ok := btree.Max(); !ok || max != 90 {
+		t.Errorf("Max expected 90, got %d", max)
+	}
+
+	btree.Delete(10)
+	btree.Delete(90)
+	if min, _ := btree.Min(); min != 20 {
+		t.Errorf("Min after delete expected 20, got %d", min)
+	}
+	if max, _ := btree.Max(); max != 80 {
+		t.Errorf("Max after delete expected 80, got %d", max)
+	}
+}
+
+// collectKeysReverse drains an iterator backwards into a slice
+func collectKeysReverse[V any](it Iterator[int, V]) []int {
+	var keys []int
+	for ; it.Valid(); it.Prev() {
+		keys = append(keys, it.Key())
+	}
+	return keys
+}
+
+func TestReverseCursor(t *testing.T) {
+	btree := newBTree[int, int](4)
+	if btree.Last().Valid() {
+		t.Errorf("Last on an empty tree should not be valid")
+	}
+	for i := 0; i < 100; i += 2 {
+		btree.Put(i, i*10)
+	}
+
+	c := btree.SeekLast(40)
+	if !c.Valid() || c.Key() != 40 || c.Value() != 400 {
+		t.Fatalf("SeekLast(40) expected (40, 400)")
+	}
+	c = btree.SeekLast(41)
+	if !c.Valid() || c.Key() != 40 {
+		t.Fatalf("SeekLast(41) expected 40")
+	}
+	if btree.SeekLast(-1).Valid() {
+		t.Errorf("SeekLast before the first key should not be valid")
+	}
+
+	keys := collectKeysReverse(btree.SeekLast(7))
+	if !equalKeys(keys, []int{6, 4, 2, 0}) {
+		t.Errorf("Reverse scan from 7 got %v", keys)
+	}
+
+	// Last N keys
+	last := btree.Last()
+	var top []int
+	for i := 0; i < 3 && last.Valid(); i++ {
+		top = append(top, last.Key())
+		last.Prev()
+	}
+	if !equalKeys(top, []int{98, 96, 94}) {
+		t.Errorf("Last three keys got %v", top)
+	}
+
+	// Direction can change mid scan
+	c = btree.Seek(50)
+	c.Next()
+	c.Prev()
+	c.Prev()
+	if !c.Valid() || c.Key() != 48 {
+		t.Errorf("Next then Prev twice from 50 expected 48")
+	}
+}
+
+func TestReverseRange(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	btree := newBTree[int, int](4)
+	present := make(map[int]bool)
+	for i := 0; i < 600; i++ {
+		k := rng.Intn(500)
+		if rng.Intn(4) == 0 {
+			btree.Delete(k)
+			delete(present, k)
+		} else {
+			btree.Insert(k)
+			present[k] = true
+		}
+	}
+
+	for trial := 0; trial < 200; trial++ {
+		lo, hi := rng.Intn(520)-10, rng.Intn(520)-10
+		loInc, hiInc := rng.Intn(2) == 0, rng.Intn(2) == 0
+
+		var expected []int
+		for k := hi; k >= lo; k-- {
+			if !present[k] || (k == lo && !loInc) || (k == hi && !hiInc) {
+				continue
+			}
+			expected = append(expected, k)
+		}
+
+		got := collectKeysReverse(btree.ReverseRange(lo, hi, loInc, hiInc))
+		if !equalKeys(got, expected) {
+			t.Fatalf("ReverseRange(%d, %d, %v, %v) = %v, expected %v", lo, hi, loInc, hiInc, got, expected)
+		}
+	}
+
+	// The backward chain must mirror the forward chain
+	forward := collectKeys(btree.First())
+	backward := collectKeysReverse(btree.Last())
+	if len(forward) != len(backward) {
+		t.Fatalf("Forward chain has %d keys, backward chain has %d", len(forward), len(backward))
+	}
+	for i := range forward {
+		if forward[i] != backward[len(backward)-1-i] {
+			t.Fatalf("Leaf chains disagree at %d", i)
+		}
+	}
+}
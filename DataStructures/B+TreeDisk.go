@@ -0,0 +1,1080 @@
+package DataStructures
+
+import (
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// DiskBTree is a B+ tree whose nodes are pages of a page file, see pager.go.
+// Children and leaf links are PageIDs instead of pointers and a node is only
+// in memory while an operation uses it, so the index can be far larger than
+// RAM and is still there when the file is opened again.
+//
+// Keys and values are stored as their Codec encodes them and a node holds as
+// many as fit in its page, see B+TreeDiskPage.go, rather than a fixed
+// 'degree'. The codec sizes bound the largest entry, a page must hold at
+// least four of those.
+//
+// Operations hold a tree wide lock, reads shared and writes exclusively
+type DiskBTree[K any, V any] struct {
+	pager     *Pager
+	keys      Codec[K]
+	values    Codec[V]
+	compare   func(a, b K) int
+	separator func(lo, hi K) K // nil unless the key codec is a Separator
+
+	// Mirrored in the pager's meta area, see saveMeta
+	root   PageID // 0 until the first key is stored
+	length int
+
+	mu sync.RWMutex
+}
+
+// Codec turns values of T into bytes and back. Size is the most bytes Append
+// ever adds, it bounds how much of a page one entry can take
+type Codec[T any] interface {
+	Size() int
+	Append(dst []byte, v T) ([]byte, error)
+	Decode(src []byte) (T, error)
+}
+
+// Int64Codec stores an int64 in 8 bytes
+type Int64Codec struct{}
+
+func (Int64Codec) Size() int { return 8 }
+
+func (Int64Codec) Append(dst []byte, v int64) ([]byte, error) {
+	return binary.BigEndian.AppendUint64(dst, uint64(v)), nil
+}
+
+func (Int64Codec) Decode(src []byte) (int64, error) {
+	if len(src) != 8 {
+		return 0, fmt.Errorf("an int64 takes 8 bytes, not %d", len(src))
+	}
+	return int64(binary.BigEndian.Uint64(src)), nil
+}
+
+// Uint64Codec stores a uint64 in 8 bytes
+type Uint64Codec struct{}
+
+func (Uint64Codec) Size() int { return 8 }
+
+func (Uint64Codec) Append(dst []byte, v uint64) ([]byte, error) {
+	return binary.BigEndian.AppendUint64(dst, v), nil
+}
+
+func (Uint64Codec) Decode(src []byte) (uint64, error) {
+	if len(src) != 8 {
+		return 0, fmt.Errorf("a uint64 takes 8 bytes, not %d", len(src))
+	}
+	return binary.BigEndian.Uint64(src), nil
+}
+
+// StringCodec stores strings of up to Max bytes as they are
+type StringCodec struct {
+	Max int
+}
+
+func (c StringCodec) Size() int { return c.Max }
+
+func (c StringCodec) Append(dst []byte, v string) ([]byte, error) {
+	if len(v) > c.Max {
+		return dst, fmt.Errorf("%w: %d byte string, the limit is %d", ErrValueTooLarge, len(v), c.Max)
+	}
+	return append(dst, v...), nil
+}
+
+func (c StringCodec) Decode(src []byte) (string, error) {
+	return string(src), nil
+}
+
+var (
+	ErrValueTooLarge = errors.New("btree: value does not fit its codec")
+	ErrPageTooSmall  = errors.New("btree: page too small for four entries")
+	ErrDiskLayout    = errors.New("btree: page file holds a different key or value layout")
+)
+
+// The tree's part of the pager meta area: magic, root, length, key size,
+// value size and the node format
+var diskTreeMagic = [4]byte{'B', 'P', 'T', 'D'}
+
+const (
+	diskMetaSize   = 4 + 8 + 8 + 4 + 4 + 2
+	diskTreeFormat = 1
+)
+
+// OpenDiskBTree opens the tree stored in the page file at 'path', or creates
+// an empty one. 'pageSize' is used for a new file, 0 picks DefaultPageSize.
+// The codecs must have the sizes the file was created with
+func OpenDiskBTree[K Ordered, V any](path string, pageSize int, keys Codec[K], values Codec[V]) (*DiskBTree[K, V], error) {
+	pager, err := OpenPager(path, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	t, err := newDiskBTree(pager, keys, values, cmp.Compare[K])
+	if err != nil {
+		pager.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+func newDiskBTree[K any, V any](pager *Pager, keys Codec[K], values Codec[V], compare func(a, b K) int) (*DiskBTree[K, V], error) {
+	ks, vs := keys.Size(), values.Size()
+	t := &DiskBTree[K, V]{pager: pager, keys: keys, values: values, compare: compare}
+	if s, ok := keys.(Separator[K]); ok {
+		t.separator = s.Separator
+	}
+	largest := max(2*diskLengthSize+ks+vs, diskLengthSize+ks+diskChildSize)
+	if ks > 0xFFFF || vs > 0xFFFF || 4*largest > pager.PageSize()-diskNodeHeader-diskChildSize {
+		return nil, fmt.Errorf("%w: %d byte pages, %d byte keys and %d byte values", ErrPageTooSmall, pager.PageSize(), ks, vs)
+	}
+
+	meta := pager.Meta()
+	switch [4]byte(meta[:4]) {
+	case [4]byte{}:
+		// A fresh file
+		return t, t.saveMeta()
+	case diskTreeMagic:
+	default:
+		return nil, fmt.Errorf("%w: not a tree", ErrDiskLayout)
+	}
+	if format := binary.BigEndian.Uint16(meta[28:]); format != diskTreeFormat {
+		return nil, fmt.Errorf("%w: node format %d", ErrDiskLayout, format)
+	}
+	if int(binary.BigEndian.Uint32(meta[20:])) != ks || int(binary.BigEndian.Uint32(meta[24:])) != vs {
+		return nil, fmt.Errorf("%w: file has %d byte keys and %d byte values", ErrDiskLayout,
+			binary.BigEndian.Uint32(meta[20:]), binary.BigEndian.Uint32(meta[24:]))
+	}
+	t.root = PageID(binary.BigEndian.Uint64(meta[4:]))
+	t.length = int(binary.BigEndian.Uint64(meta[12:]))
+	if int(t.root) >= pager.PageCount() {
+		return nil, fmt.Errorf("%w: root page %d", ErrPagerCorrupted, t.root)
+	}
+	return t, nil
+}
+
+// saveMeta writes the root and length to the header page
+func (t *DiskBTree[K, V]) saveMeta() error {
+	meta := make([]byte, diskMetaSize)
+	copy(meta, diskTreeMagic[:])
+	binary.BigEndian.PutUint64(meta[4:], uint64(t.root))
+	binary.BigEndian.PutUint64(meta[12:], uint64(t.length))
+	binary.BigEndian.PutUint32(meta[20:], uint32(t.keys.Size()))
+	binary.BigEndian.PutUint32(meta[24:], uint32(t.values.Size()))
+	binary.BigEndian.PutUint16(meta[28:], diskTreeFormat)
+	return t.pager.SetMeta(meta)
+}
+
+// Sync flushes the tree to stable storage
+func (t *DiskBTree[K, V]) Sync() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.pager.Sync()
+}
+
+// Close syncs and closes the page file, the tree cannot be used afterwards
+func (t *DiskBTree[K, V]) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.pager.Close()
+}
+
+// Len returns the number of keys in the tree
+func (t *DiskBTree[K, V]) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.length
+}
+
+// load reads and decodes page 'id'
+func (t *DiskBTree[K, V]) load(id PageID) (*diskNode[K, V], error) {
+	buf := make([]byte, t.pager.PageSize())
+	if err := t.pager.Read(id, buf); err != nil {
+		return nil, err
+	}
+	return decodeNode[K, V](id, buf, t.keys)
+}
+
+// store encodes and writes back every node in 'nodes'
+func (t *DiskBTree[K, V]) store(nodes ...*diskNode[K, V]) error {
+	for _, n := range nodes {
+		buf := make([]byte, t.pager.PageSize())
+		if encoded := n.encode(); len(encoded) <= len(buf) {
+			copy(buf, encoded)
+		} else {
+			return fmt.Errorf("btree: node of %d bytes does not fit page %d", len(encoded), n.id)
+		}
+		if err := t.pager.Write(n.id, buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allocate gives every node in 'nodes' a fresh page
+func (t *DiskBTree[K, V]) allocate(nodes ...*diskNode[K, V]) error {
+	for _, n := range nodes {
+		id, err := t.pager.Allocate()
+		if err != nil {
+			return err
+		}
+		n.id = id
+	}
+	return nil
+}
+
+// childIndex picks the child of 'n' that routes 'key', equal keys go right
+func (t *DiskBTree[K, V]) childIndex(n *diskNode[K, V], key K) int {
+	found, i := searchKeys(n.keys, key, t.compare)
+	if found {
+		i++
+	}
+	return i
+}
+
+// minFill is the fill below which a node other than the root is merged or
+// topped up from a neighbour
+func (t *DiskBTree[K, V]) minFill() int {
+	return t.pager.PageSize() / 4
+}
+
+func (t *DiskBTree[K, V]) fits(n *diskNode[K, V]) bool {
+	return n.pageSize() <= t.pager.PageSize()
+}
+
+func (t *DiskBTree[K, V]) underFill(n *diskNode[K, V]) bool {
+	return n.fill() < t.minFill() || len(n.keys) == 0
+}
+
+// canLend reports whether 'n' stays full enough without entry 'i'
+func (t *DiskBTree[K, V]) canLend(n *diskNode[K, V], i int) bool {
+	return len(n.keys) > 1 && n.fill()-n.entrySize(i) >= t.minFill()
+}
+
+// findLeaf descends to the leaf that holds or would hold 'key', the caller
+// holds 'mu' and has checked the tree has a root
+func (t *DiskBTree[K, V]) findLeaf(key K) (*diskNode[K, V], error) {
+	return t.descend(func(n *diskNode[K, V]) int { return t.childIndex(n, key) })
+}
+
+// descend follows 'pick' from the root down to a leaf
+func (t *DiskBTree[K, V]) descend(pick func(*diskNode[K, V]) int) (*diskNode[K, V], error) {
+	n, err := t.load(t.root)
+	for err == nil && !n.leaf {
+		n, err = t.load(n.children[pick(n)])
+	}
+	return n, err
+}
+
+// decodeValue decodes a stored value, a failure means the page is corrupt
+func (t *DiskBTree[K, V]) decodeValue(raw []byte) (V, error) {
+	v, err := t.values.Decode(raw)
+	if err != nil {
+		return v, fmt.Errorf("%w: value: %v", ErrPagerCorrupted, err)
+	}
+	return v, nil
+}
+
+// Get returns the value stored under 'key'
+func (t *DiskBTree[K, V]) Get(key K) (V, bool, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var zero V
+	if t.root == 0 {
+		return zero, false, nil
+	}
+	leaf, err := t.findLeaf(key)
+	if err != nil {
+		return zero, false, err
+	}
+	if found, i := searchKeys(leaf.keys, key, t.compare); found {
+		v, err := t.decodeValue(leaf.values[i])
+		return v, err == nil, err
+	}
+	return zero, false, nil
+}
+
+// Search reports whether 'key' is in the tree
+func (t *DiskBTree[K, V]) Search(key K) (bool, error) {
+	_, found, err := t.Get(key)
+	return found, err
+}
+
+// Insert adds 'key' with a zero value, existing keys are left untouched
+func (t *DiskBTree[K, V]) Insert(key K) error {
+	var zero V
+	return t.put(key, zero, false)
+}
+
+// Put stores 'value' under 'key', replacing any value already there
+func (t *DiskBTree[K, V]) Put(key K, value V) error {
+	return t.put(key, value, true)
+}
+
+// encode runs 'codec' on 'v' and holds it to the codec's own size limit
+func encode[T any](codec Codec[T], v T) ([]byte, error) {
+	raw, err := codec.Append(nil, v)
+	if err == nil && len(raw) > codec.Size() {
+		err = fmt.Errorf("%w: %d bytes, the codec allows %d", ErrValueTooLarge, len(raw), codec.Size())
+	}
+	return raw, err
+}
+
+func (t *DiskBTree[K, V]) put(key K, value V, overwrite bool) error {
+	// Encode up front so a key or value that does not fit fails before any
+	// page is touched
+	rawKey, err := encode(t.keys, key)
+	if err != nil {
+		return err
+	}
+	rawValue, err := encode(t.values, value)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.root == 0 {
+		root := &diskNode[K, V]{leaf: true}
+		if err := t.allocate(root); err != nil {
+			return err
+		}
+		if err := t.store(root); err != nil {
+			return err
+		}
+		t.root = root.id
+		if err := t.saveMeta(); err != nil {
+			return err
+		}
+	}
+
+	added := false
+	root, splits, err := t.change(t.root, key, func(leaf *diskNode[K, V]) bool {
+		found, i := searchKeys(leaf.keys, key, t.compare)
+		switch {
+		case found && !overwrite:
+			return false
+		case found:
+			leaf.values[i] = rawValue
+		default:
+			leaf.keys = slices.Insert(leaf.keys, i, key)
+			leaf.raw = slices.Insert(leaf.raw, i, rawKey)
+			leaf.values = slices.Insert(leaf.values, i, rawValue)
+			added = true
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if added {
+		t.length++
+	}
+	return t.finish(root, splits)
+}
+
+// Delete removes 'key' and returns the value it held
+func (t *DiskBTree[K, V]) Delete(key K) (V, bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var zero V
+	if t.root == 0 {
+		return zero, false, nil
+	}
+	var old []byte
+	found := false
+	root, splits, err := t.change(t.root, key, func(leaf *diskNode[K, V]) bool {
+		var i int
+		if found, i = searchKeys(leaf.keys, key, t.compare); !found {
+			return false
+		}
+		old = leaf.values[i]
+		leaf.keys = slices.Delete(leaf.keys, i, i+1)
+		leaf.raw = slices.Delete(leaf.raw, i, i+1)
+		leaf.values = slices.Delete(leaf.values, i, i+1)
+		return true
+	})
+	if err != nil || !found {
+		return zero, false, err
+	}
+	t.length--
+	if err := t.finish(root, splits); err != nil {
+		return zero, true, err
+	}
+	v, err := t.decodeValue(old)
+	return v, true, err
+}
+
+// finish grows the tree by a level while the root splits, and lets a root
+// left with a single child hand the tree down to it, then saves the meta
+func (t *DiskBTree[K, V]) finish(root *diskNode[K, V], splits []diskSplit[K]) error {
+	for len(splits) > 0 {
+		top := &diskNode[K, V]{children: []PageID{t.root}}
+		for _, s := range splits {
+			top.keys = append(top.keys, s.sep)
+			top.raw = append(top.raw, s.raw)
+			top.children = append(top.children, s.right)
+		}
+		if err := t.allocate(top); err != nil {
+			return err
+		}
+		t.root = top.id
+		var err error
+		if splits, err = t.split(top); err != nil {
+			return err
+		}
+	}
+	if root != nil && !root.leaf && len(root.keys) == 0 {
+		t.root = root.children[0]
+		if err := t.pager.Free(root.id); err != nil {
+			return err
+		}
+	}
+	return t.saveMeta()
+}
+
+// diskSplit is a separator and the new node to its right, handed up to the
+// parent of a node that no longer fit its page
+type diskSplit[K any] struct {
+	sep   K
+	raw   []byte
+	right PageID
+}
+
+// change runs 'op' on the leaf that holds or would hold 'key' below page 'id'
+// and repairs the path on the way back up: a node that outgrew its page is
+// split, and a child left underfilled is merged with or topped up from a
+// neighbour. 'op' reports whether it changed the leaf.
+// It returns the node as stored and the splits its parent must take, or a nil
+// node if the node itself did not change
+func (t *DiskBTree[K, V]) change(id PageID, key K, op func(leaf *diskNode[K, V]) bool) (*diskNode[K, V], []diskSplit[K], error) {
+	n, err := t.load(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if n.leaf {
+		if !op(n) {
+			return nil, nil, nil
+		}
+		splits, err := t.split(n)
+		return n, splits, err
+	}
+
+	i := t.childIndex(n, key)
+	child, splits, err := t.change(n.children[i], key, op)
+	switch {
+	case err != nil || child == nil:
+		return nil, nil, err
+	case len(splits) > 0:
+		for j, s := range splits {
+			n.keys = slices.Insert(n.keys, i+j, s.sep)
+			n.raw = slices.Insert(n.raw, i+j, s.raw)
+			n.children = slices.Insert(n.children, i+j+1, s.right)
+		}
+	case t.underFill(child):
+		if err := t.rebalance(n, i, child); err != nil {
+			return nil, nil, err
+		}
+	default:
+		// The child changed but still fits and is full enough, 'n' is as it was
+		return nil, nil, nil
+	}
+	splits, err = t.split(n)
+	return n, splits, err
+}
+
+// split stores 'n', first cutting it into as many nodes as it takes for each
+// to fit a page. Adding one key can cost a node its shared prefix and grow it
+// by more than a page, so there may be more than two
+func (t *DiskBTree[K, V]) split(n *diskNode[K, V]) ([]diskSplit[K], error) {
+	pieces, splits := t.cut(n)
+	if len(pieces) == 1 {
+		return nil, t.store(n)
+	}
+
+	// pieces[0] is 'n' itself and keeps its page
+	if err := t.allocate(pieces[1:]...); err != nil {
+		return nil, err
+	}
+	for j := range splits {
+		splits[j].right = pieces[j+1].id
+	}
+	if n.leaf {
+		last := pieces[len(pieces)-1]
+		last.next = n.next
+		for j := 1; j < len(pieces); j++ {
+			pieces[j-1].next = pieces[j].id
+			pieces[j].prev = pieces[j-1].id
+		}
+		if last.next != 0 {
+			next, err := t.load(last.next)
+			if err != nil {
+				return nil, err
+			}
+			next.prev = last.id
+			if err := t.store(next); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return splits, t.store(pieces...)
+}
+
+// cut halves 'n' until every piece fits a page. 'n' becomes the first piece,
+// the separators come back in order without their right page ids
+func (t *DiskBTree[K, V]) cut(n *diskNode[K, V]) ([]*diskNode[K, V], []diskSplit[K]) {
+	if t.fits(n) {
+		return []*diskNode[K, V]{n}, nil
+	}
+	sep, right := t.halve(n)
+	leftPieces, leftSplits := t.cut(n)
+	rightPieces, rightSplits := t.cut(right)
+	splits := append(append(leftSplits, sep), rightSplits...)
+	return append(leftPieces, rightPieces...), splits
+}
+
+// halve moves the upper half of 'n', by fill rather than by count, to a new
+// node. Like Split on the in-memory tree a leaf copies a separator up, here
+// the shortest one the key codec allows, and an internal node moves its
+// middle key up
+func (t *DiskBTree[K, V]) halve(n *diskNode[K, V]) (diskSplit[K], *diskNode[K, V]) {
+	half, m := n.fill()/2, 0
+	for acc := 0; m < len(n.keys)-1 && acc < half; m++ {
+		acc += n.entrySize(m)
+	}
+	m = max(m, 1)
+
+	right := &diskNode[K, V]{leaf: n.leaf}
+	if n.leaf {
+		right.keys = slices.Clone(n.keys[m:])
+		right.raw = slices.Clone(n.raw[m:])
+		right.values = slices.Clone(n.values[m:])
+		n.keys, n.raw, n.values = n.keys[:m:m], n.raw[:m:m], n.values[:m:m]
+		return t.separate(n.keys[m-1], right.keys[0], right.raw[0]), right
+	}
+	m = min(m, len(n.keys)-2)
+	sep := diskSplit[K]{sep: n.keys[m], raw: n.raw[m]}
+	right.keys = slices.Clone(n.keys[m+1:])
+	right.raw = slices.Clone(n.raw[m+1:])
+	right.children = slices.Clone(n.children[m+1:])
+	n.keys, n.raw, n.children = n.keys[:m:m], n.raw[:m:m], n.children[:m+1:m+1]
+	return sep, right
+}
+
+// separate picks the separator between two neighbouring leaves whose keys end
+// at 'lo' and start at 'hi'
+func (t *DiskBTree[K, V]) separate(lo, hi K, rawHi []byte) diskSplit[K] {
+	if t.separator != nil {
+		sep := t.separator(lo, hi)
+		if raw, err := encode(t.keys, sep); err == nil {
+			return diskSplit[K]{sep: sep, raw: raw}
+		}
+	}
+	return diskSplit[K]{sep: hi, raw: rawHi}
+}
+
+// rebalance fixes the underfilled children[i] of 'parent' together with a
+// neighbour, the left one when there is one. The two are merged when they fit
+// one page, otherwise entries move over from the neighbour one at a time
+// until the child is full enough. The caller stores 'parent'
+func (t *DiskBTree[K, V]) rebalance(parent *diskNode[K, V], i int, child *diskNode[K, V]) error {
+	s, sibling := i-1, i-1 // the separator between the pair, and the neighbour
+	if i == 0 {
+		s, sibling = 0, 1
+	}
+	other, err := t.load(parent.children[sibling])
+	if err != nil {
+		return err
+	}
+	left, right := other, child
+	if i == 0 {
+		left, right = child, other
+	}
+
+	if merged := joined(parent, s, left, right); t.fits(merged) {
+		return t.join(parent, s, merged, right)
+	}
+	if child == left {
+		for t.underFill(left) && t.canLend(right, 0) {
+			t.shiftLeft(parent, s, left, right)
+		}
+	} else {
+		for t.underFill(right) && t.canLend(left, len(left.keys)-1) {
+			t.shiftRight(parent, s, left, right)
+		}
+	}
+	return t.store(left, right)
+}
+
+// shiftRight moves the last entry of 'left' to the front of 'right', its
+// neighbour across parent.keys[sep]
+func (t *DiskBTree[K, V]) shiftRight(parent *diskNode[K, V], sep int, left, right *diskNode[K, V]) {
+	last := len(left.keys) - 1
+	if left.leaf {
+		right.keys = slices.Insert(right.keys, 0, left.keys[last])
+		right.raw = slices.Insert(right.raw, 0, left.raw[last])
+		right.values = slices.Insert(right.values, 0, left.values[last])
+		left.keys, left.raw, left.values = left.keys[:last], left.raw[:last], left.values[:last]
+		s := t.separate(left.keys[last-1], right.keys[0], right.raw[0])
+		parent.keys[sep], parent.raw[sep] = s.sep, s.raw
+		return
+	}
+	right.keys = slices.Insert(right.keys, 0, parent.keys[sep])
+	right.raw = slices.Insert(right.raw, 0, parent.raw[sep])
+	right.children = slices.Insert(right.children, 0, left.children[last+1])
+	parent.keys[sep], parent.raw[sep] = left.keys[last], left.raw[last]
+	left.keys, left.raw, left.children = left.keys[:last], left.raw[:last], left.children[:last+1]
+}
+
+// shiftLeft moves the first entry of 'right' to the end of 'left'
+func (t *DiskBTree[K, V]) shiftLeft(parent *diskNode[K, V], sep int, left, right *diskNode[K, V]) {
+	if left.leaf {
+		left.keys = append(left.keys, right.keys[0])
+		left.raw = append(left.raw, right.raw[0])
+		left.values = append(left.values, right.values[0])
+		right.keys, right.raw, right.values = right.keys[1:], right.raw[1:], right.values[1:]
+		s := t.separate(left.keys[len(left.keys)-1], right.keys[0], right.raw[0])
+		parent.keys[sep], parent.raw[sep] = s.sep, s.raw
+		return
+	}
+	left.keys = append(left.keys, parent.keys[sep])
+	left.raw = append(left.raw, parent.raw[sep])
+	left.children = append(left.children, right.children[0])
+	parent.keys[sep], parent.raw[sep] = right.keys[0], right.raw[0]
+	right.keys, right.raw, right.children = right.keys[1:], right.raw[1:], right.children[1:]
+}
+
+// joined builds the node 'left' and 'right', neighbours across
+// parent.keys[sep], would become if merged. It keeps the left page
+func joined[K any, V any](parent *diskNode[K, V], sep int, left, right *diskNode[K, V]) *diskNode[K, V] {
+	merged := &diskNode[K, V]{id: left.id, leaf: left.leaf, prev: left.prev, next: right.next}
+	merged.keys = slices.Clone(left.keys)
+	merged.raw = slices.Clone(left.raw)
+	if left.leaf {
+		merged.values = append(slices.Clone(left.values), right.values...)
+	} else {
+		merged.keys = append(merged.keys, parent.keys[sep])
+		merged.raw = append(merged.raw, parent.raw[sep])
+		merged.children = append(slices.Clone(left.children), right.children...)
+	}
+	merged.keys = append(merged.keys, right.keys...)
+	merged.raw = append(merged.raw, right.raw...)
+	return merged
+}
+
+// join replaces 'left' and 'right' under 'parent' with 'merged' and frees the
+// right page
+func (t *DiskBTree[K, V]) join(parent *diskNode[K, V], sep int, merged, right *diskNode[K, V]) error {
+	if merged.leaf && merged.next != 0 {
+		next, err := t.load(merged.next)
+		if err != nil {
+			return err
+		}
+		next.prev = merged.id
+		if err := t.store(next); err != nil {
+			return err
+		}
+	}
+	parent.keys = slices.Delete(parent.keys, sep, sep+1)
+	parent.raw = slices.Delete(parent.raw, sep, sep+1)
+	parent.children = slices.Delete(parent.children, sep+1, sep+2)
+	if err := t.store(merged); err != nil {
+		return err
+	}
+	return t.pager.Free(right.id)
+}
+
+// Validate checks the same invariants as BTree.Validate, minus the parent
+// pointers and subtree counts a disk tree does not keep, and that Len matches
+// the keys found. Leaves other than the root must be a quarter full and
+// internal nodes hold at least one key. It reads every page of the tree
+func (t *DiskBTree[K, V]) Validate() error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.root == 0 {
+		if t.length != 0 {
+			return &ValidationError{Rule: "length", Detail: fmt.Sprintf("no root but a length of %d", t.length)}
+		}
+		return nil
+	}
+	v := &diskValidator[K, V]{tree: t, leafDepth: -1}
+	if err := v.walk(t.root, nil, 0, keyBound[K]{}, keyBound[K]{}); err != nil {
+		return err
+	}
+	for i, leaf := range v.leaves {
+		var next, prev PageID
+		if i+1 < len(v.leaves) {
+			next = v.leaves[i+1].id
+		}
+		if i > 0 {
+			prev = v.leaves[i-1].id
+		}
+		if leaf.next != next {
+			return &ValidationError{Path: v.paths[i], Rule: "leaf chain", Detail: "next does not point at the following leaf"}
+		}
+		if leaf.prev != prev {
+			return &ValidationError{Path: v.paths[i], Rule: "leaf chain", Detail: "prev does not point at the preceding leaf"}
+		}
+	}
+	if v.keys != t.length {
+		return &ValidationError{Rule: "length", Detail: fmt.Sprintf("%d keys but a length of %d", v.keys, t.length)}
+	}
+	return nil
+}
+
+type diskValidator[K any, V any] struct {
+	tree      *DiskBTree[K, V]
+	leafDepth int
+	leaves    []*diskNode[K, V]
+	paths     [][]int
+	keys      int
+}
+
+func (v *diskValidator[K, V]) walk(id PageID, path []int, depth int, lo, hi keyBound[K]) error {
+	t := v.tree
+	fail := func(rule, format string, args ...any) error {
+		return &ValidationError{Path: append([]int{}, path...), Rule: rule, Detail: fmt.Sprintf(format, args...)}
+	}
+	n, err := t.load(id)
+	if err != nil {
+		return fail("page", "%v", err)
+	}
+	for i, key := range n.keys {
+		if i > 0 && t.compare(n.keys[i-1], key) >= 0 {
+			return fail("key order", "keys %d and %d are out of order", i-1, i)
+		}
+		if (lo.set && t.compare(key, lo.key) < 0) || (hi.set && t.compare(key, hi.key) >= 0) {
+			return fail("separator bound", "key %d lies outside its parent's separators", i)
+		}
+	}
+
+	if n.leaf {
+		if id != t.root && n.fill() < t.minFill() {
+			return fail("node fill", "leaf fill %d, at least %d needed", n.fill(), t.minFill())
+		}
+		if v.leafDepth == -1 {
+			v.leafDepth = depth
+		} else if depth != v.leafDepth {
+			return fail("leaf depth", "leaf at depth %d, others at %d", depth, v.leafDepth)
+		}
+		v.leaves = append(v.leaves, n)
+		v.paths = append(v.paths, append([]int{}, path...))
+		v.keys += len(n.keys)
+		return nil
+	}
+	if len(n.keys) == 0 {
+		return fail("node fill", "internal node without separators")
+	}
+	for i, child := range n.children {
+		childLo, childHi := lo, hi
+		if i > 0 {
+			childLo = keyBound[K]{set: true, key: n.keys[i-1]}
+		}
+		if i < len(n.keys) {
+			childHi = keyBound[K]{set: true, key: n.keys[i]}
+		}
+		if err := v.walk(child, append(path, i), depth+1, childLo, childHi); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DiskCursor is a position in the leaf chain of a DiskBTree. It keeps a copy of
+// one leaf and reads the next page only when it walks off the end, holding no
+// lock in between. Writes made while a scan is under way may make it skip or
+// repeat keys, or stop it with an error. Check Err once Valid turns false
+type DiskCursor[K any, V any] struct {
+	tree  *DiskBTree[K, V]
+	node  *diskNode[K, V]
+	index int
+	err   error
+
+	// optional bounds, the cursor becomes invalid once one is passed
+	bounded     bool
+	lo, hi      K
+	loInclusive bool
+	hiInclusive bool
+}
+
+// Valid reports whether the cursor points at an entry
+func (c *DiskCursor[K, V]) Valid() bool {
+	if c.err != nil || c.node == nil || c.index < 0 || c.index >= len(c.node.keys) {
+		return false
+	}
+	if !c.bounded {
+		return true
+	}
+	key := c.node.keys[c.index]
+	lo, hi := c.tree.compare(key, c.lo), c.tree.compare(key, c.hi)
+	aboveLo := lo > 0 || (c.loInclusive && lo == 0)
+	belowHi := hi < 0 || (c.hiInclusive && hi == 0)
+	return aboveLo && belowHi
+}
+
+// Err returns the error that stopped the cursor, if any
+func (c *DiskCursor[K, V]) Err() error {
+	return c.err
+}
+
+// Next moves the cursor to the following entry
+func (c *DiskCursor[K, V]) Next() {
+	c.index++
+	c.skipForward()
+}
+
+// Prev moves the cursor to the preceding entry
+func (c *DiskCursor[K, V]) Prev() {
+	c.index--
+	c.skipBackward()
+}
+
+// Key returns the key under the cursor
+func (c *DiskCursor[K, V]) Key() K {
+	return c.node.keys[c.index]
+}
+
+// Value returns the value under the cursor. A value that fails to decode
+// stops the cursor with an error
+func (c *DiskCursor[K, V]) Value() V {
+	v, err := c.tree.decodeValue(c.node.values[c.index])
+	if err != nil && c.err == nil {
+		c.err = err
+	}
+	return v
+}
+
+// skipForward moves on through the 'next' pages while the cursor is past the
+// end of its leaf
+func (c *DiskCursor[K, V]) skipForward() {
+	for c.err == nil && c.node != nil && c.index >= len(c.node.keys) && c.node.next != 0 {
+		c.node, c.err = c.tree.loadShared(c.node.next)
+		c.index = 0
+	}
+}
+
+// skipBackward moves back through the 'prev' pages while the cursor is before
+// the start of its leaf
+func (c *DiskCursor[K, V]) skipBackward() {
+	for c.err == nil && c.node != nil && c.index < 0 && c.node.prev != 0 {
+		c.node, c.err = c.tree.loadShared(c.node.prev)
+		if c.node != nil {
+			c.index = len(c.node.keys) - 1
+		}
+	}
+}
+
+// loadShared is load for a caller that does not hold 'mu'
+func (t *DiskBTree[K, V]) loadShared(id PageID) (*diskNode[K, V], error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.load(id)
+}
+
+// cursorAt descends with 'pick' and places a cursor in the leaf it reaches
+func (t *DiskBTree[K, V]) cursorAt(pick func(*diskNode[K, V]) int, index func(*diskNode[K, V]) int) *DiskCursor[K, V] {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	c := &DiskCursor[K, V]{tree: t}
+	if t.root == 0 {
+		return c
+	}
+	c.node, c.err = t.descend(pick)
+	if c.node != nil {
+		c.index = index(c.node)
+	}
+	return c
+}
+
+// Seek returns a cursor on the first key >= 'key'
+func (t *DiskBTree[K, V]) Seek(key K) *DiskCursor[K, V] {
+	c := t.cursorAt(
+		func(n *diskNode[K, V]) int { return t.childIndex(n, key) },
+		func(n *diskNode[K, V]) int { _, i := searchKeys(n.keys, key, t.compare); return i },
+	)
+	c.skipForward()
+	return c
+}
+
+// SeekLast returns a cursor on the last key <= 'key'
+func (t *DiskBTree[K, V]) SeekLast(key K) *DiskCursor[K, V] {
+	c := t.cursorAt(
+		func(n *diskNode[K, V]) int { return t.childIndex(n, key) },
+		func(n *diskNode[K, V]) int {
+			found, i := searchKeys(n.keys, key, t.compare)
+			if found {
+				return i
+			}
+			return i - 1
+		},
+	)
+	c.skipBackward()
+	return c
+}
+
+// First returns a cursor on the smallest key
+func (t *DiskBTree[K, V]) First() *DiskCursor[K, V] {
+	c := t.cursorAt(
+		func(n *diskNode[K, V]) int { return 0 },
+		func(n *diskNode[K, V]) int { return 0 },
+	)
+	c.skipForward()
+	return c
+}
+
+// Last returns a cursor on the largest key
+func (t *DiskBTree[K, V]) Last() *DiskCursor[K, V] {
+	c := t.cursorAt(
+		func(n *diskNode[K, V]) int { return len(n.children) - 1 },
+		func(n *diskNode[K, V]) int { return len(n.keys) - 1 },
+	)
+	c.skipBackward()
+	return c
+}
+
+// Range returns a cursor over the keys between 'lo' and 'hi'
+func (t *DiskBTree[K, V]) Range(lo, hi K, loInclusive, hiInclusive bool) *DiskCursor[K, V] {
+	c := t.Seek(lo)
+	c.bounded, c.lo, c.hi = true, lo, hi
+	c.loInclusive, c.hiInclusive = loInclusive, hiInclusive
+	if !loInclusive && c.err == nil && c.node != nil && c.index < len(c.node.keys) && t.compare(c.node.keys[c.index], lo) == 0 {
+		c.Next()
+	}
+	return c
+}
+
+// Min returns the smallest key
+func (t *DiskBTree[K, V]) Min() (K, bool, error) {
+	return cursorKey(t.First())
+}
+
+// Max returns the largest key
+func (t *DiskBTree[K, V]) Max() (K, bool, error) {
+	return cursorKey(t.Last())
+}
+
+func cursorKey[K any, V any](c *DiskCursor[K, V]) (K, bool, error) {
+	if !c.Valid() {
+		var zero K
+		return zero, false, c.Err()
+	}
+	return c.Key(), true, nil
+}
+
+// Display prints the keys of the tree level by level, like BTree.Display
+func (t *DiskBTree[K, V]) Display() error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.root == 0 {
+		fmt.Println("Empty tree. No levels to print.")
+		return nil
+	}
+	level := []PageID{t.root}
+	for depth := 0; len(level) > 0; depth++ {
+		var line []string
+		var below []PageID
+		for _, id := range level {
+			n, err := t.load(id)
+			if err != nil {
+				return err
+			}
+			for _, key := range n.keys {
+				line = append(line, fmt.Sprintf("%v", key))
+			}
+			below = append(below, n.children...)
+		}
+		fmt.Printf("Level %d: %s\n", depth, join(line, ", "))
+		level = below
+	}
+	return nil
+}
+
+// DiskTreeAdapter shows a DiskBTree through the DiskTree interface, so code
+// written against BTree can run on either. DiskTree methods cannot fail: an
+// operation that hits an error reports nothing found and the first such
+// error is kept for Err. Cursors are *DiskCursor and report their own errors
+type DiskTreeAdapter[K any, V any] struct {
+	tree *DiskBTree[K, V]
+
+	mu  sync.Mutex
+	err error
+}
+
+// AsDiskTree returns 't' behind the DiskTree interface, see DiskTreeAdapter
+func (t *DiskBTree[K, V]) AsDiskTree() *DiskTreeAdapter[K, V] {
+	return &DiskTreeAdapter[K, V]{tree: t}
+}
+
+// Err returns the first error an operation through the adapter hit, if any
+func (a *DiskTreeAdapter[K, V]) Err() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.err
+}
+
+// keep records 'err' unless an earlier error is already kept
+func (a *DiskTreeAdapter[K, V]) keep(err error) {
+	if err == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.err == nil {
+		a.err = err
+	}
+}
+
+func (a *DiskTreeAdapter[K, V]) Insert(key K) { a.keep(a.tree.Insert(key)) }
+
+func (a *DiskTreeAdapter[K, V]) Put(key K, value V) { a.keep(a.tree.Put(key, value)) }
+
+func (a *DiskTreeAdapter[K, V]) Get(key K) (V, bool) {
+	v, found, err := a.tree.Get(key)
+	a.keep(err)
+	return v, found
+}
+
+func (a *DiskTreeAdapter[K, V]) Delete(key K) (V, bool) {
+	v, found, err := a.tree.Delete(key)
+	a.keep(err)
+	return v, found
+}
+
+func (a *DiskTreeAdapter[K, V]) Search(key K) bool {
+	found, err := a.tree.Search(key)
+	a.keep(err)
+	return found
+}
+
+func (a *DiskTreeAdapter[K, V]) Display() { a.keep(a.tree.Display()) }
+
+func (a *DiskTreeAdapter[K, V]) Seek(key K) Iterator[K, V] { return a.tree.Seek(key) }
+
+func (a *DiskTreeAdapter[K, V]) SeekLast(key K) Iterator[K, V] { return a.tree.SeekLast(key) }
+
+func (a *DiskTreeAdapter[K, V]) Range(lo, hi K, loInclusive, hiInclusive bool) Iterator[K, V] {
+	return a.tree.Range(lo, hi, loInclusive, hiInclusive)
+}
+
+func (a *DiskTreeAdapter[K, V]) Min() (K, bool) {
+	k, found, err := a.tree.Min()
+	a.keep(err)
+	return k, found
+}
+
+func (a *DiskTreeAdapter[K, V]) Max() (K, bool) {
+	k, found, err := a.tree.Max()
+	a.keep(err)
+	return k, found
+}
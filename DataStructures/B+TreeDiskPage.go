@@ -0,0 +1,239 @@
+package DataStructures
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Page layout of a DiskBTree node. Keys are stored as the bytes their codec
+// writes, and the longest prefix shared by every key of the node is written
+// once in the header, each key only keeps what follows it. Long keys that
+// share a prefix, URLs or composite keys, then take a fraction of the space
+// and a page holds that many more of them.
+//
+//	kind   byte     diskKindLeaf or diskKindInternal
+//	       byte     unused
+//	count  uint16   number of keys
+//	next   uint64   leaves only, 0 for the last leaf
+//	prev   uint64   leaves only, 0 for the first leaf
+//	prefix uint16   length of the shared prefix, followed by its bytes
+//
+// A leaf continues with count entries of
+//
+//	suffix uint16 length, suffix bytes, value uint16 length, value bytes
+//
+// and an internal node with its first child as a uint64, then count entries of
+//
+//	suffix uint16 length, suffix bytes, child uint64
+const (
+	diskKindLeaf     = 1
+	diskKindInternal = 2
+	diskNodeHeader   = 22
+	diskChildSize    = 8
+	diskLengthSize   = 2
+)
+
+// diskNode is a page decoded into memory. Changes only reach the file through store
+type diskNode[K any, V any] struct {
+	id         PageID
+	leaf       bool
+	keys       []K
+	raw        [][]byte // keys as their codec encodes them, one per key
+	values     [][]byte // leaves only, encoded
+	children   []PageID // internal nodes only, len(keys)+1 of them
+	next, prev PageID   // leaves only
+}
+
+// sharedPrefix is the length of the longest prefix of every key in 'raw'
+func sharedPrefix(raw [][]byte) int {
+	if len(raw) == 0 {
+		return 0
+	}
+	p := len(raw[0])
+	for _, key := range raw[1:] {
+		p = min(p, len(key))
+		for i := 0; i < p; i++ {
+			if key[i] != raw[0][i] {
+				p = i
+				break
+			}
+		}
+	}
+	return p
+}
+
+// encode lays 'n' out as a page, without the padding up to the page size
+func (n *diskNode[K, V]) encode() []byte {
+	p := sharedPrefix(n.raw)
+	buf := make([]byte, diskNodeHeader)
+	binary.BigEndian.PutUint16(buf[2:], uint16(len(n.keys)))
+	binary.BigEndian.PutUint16(buf[20:], uint16(p))
+	if n.leaf {
+		buf[0] = diskKindLeaf
+		binary.BigEndian.PutUint64(buf[4:], uint64(n.next))
+		binary.BigEndian.PutUint64(buf[12:], uint64(n.prev))
+	} else {
+		buf[0] = diskKindInternal
+	}
+	if p > 0 {
+		buf = append(buf, n.raw[0][:p]...)
+	}
+	if !n.leaf {
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n.children[0]))
+	}
+	for i, key := range n.raw {
+		buf = binary.BigEndian.AppendUint16(buf, uint16(len(key)-p))
+		buf = append(buf, key[p:]...)
+		if n.leaf {
+			buf = binary.BigEndian.AppendUint16(buf, uint16(len(n.values[i])))
+			buf = append(buf, n.values[i]...)
+		} else {
+			buf = binary.BigEndian.AppendUint64(buf, uint64(n.children[i+1]))
+		}
+	}
+	return buf
+}
+
+// pageSize is how many bytes 'n' takes once encoded
+func (n *diskNode[K, V]) pageSize() int {
+	p := sharedPrefix(n.raw)
+	size := diskNodeHeader + p
+	if !n.leaf {
+		size += diskChildSize
+	}
+	for i, key := range n.raw {
+		size += diskLengthSize + len(key) - p
+		if n.leaf {
+			size += diskLengthSize + len(n.values[i])
+		} else {
+			size += diskChildSize
+		}
+	}
+	return size
+}
+
+// fill measures a node as if it were stored without prefix compression. Unlike
+// pageSize it changes by exactly an entry's size when the entry moves, which
+// is what lets rebalancing promise every leaf stays at least a quarter full
+func (n *diskNode[K, V]) fill() int {
+	size := diskNodeHeader
+	if !n.leaf {
+		size += diskChildSize
+	}
+	for i := range n.raw {
+		size += n.entrySize(i)
+	}
+	return size
+}
+
+// entrySize is fill's share of entry 'i'
+func (n *diskNode[K, V]) entrySize(i int) int {
+	if n.leaf {
+		return 2*diskLengthSize + len(n.raw[i]) + len(n.values[i])
+	}
+	return diskLengthSize + len(n.raw[i]) + diskChildSize
+}
+
+// decodeNode parses page 'id'. Every length is checked against the page, so a
+// corrupt page gives an error rather than a panic
+func decodeNode[K any, V any](id PageID, buf []byte, keys Codec[K]) (*diskNode[K, V], error) {
+	corrupt := func(what string) error {
+		return fmt.Errorf("%w: page %d: %s", ErrPagerCorrupted, id, what)
+	}
+	if len(buf) < diskNodeHeader || (buf[0] != diskKindLeaf && buf[0] != diskKindInternal) {
+		return nil, corrupt("not a tree node")
+	}
+	n := &diskNode[K, V]{id: id, leaf: buf[0] == diskKindLeaf}
+	count := int(binary.BigEndian.Uint16(buf[2:]))
+	if n.leaf {
+		n.next = PageID(binary.BigEndian.Uint64(buf[4:]))
+		n.prev = PageID(binary.BigEndian.Uint64(buf[12:]))
+	}
+
+	rest := buf[diskNodeHeader:]
+	take := func(size int) ([]byte, bool) {
+		if size > len(rest) {
+			return nil, false
+		}
+		out := rest[:size]
+		rest = rest[size:]
+		return out, true
+	}
+	takeLength := func() (int, bool) {
+		b, ok := take(diskLengthSize)
+		if !ok {
+			return 0, false
+		}
+		return int(binary.BigEndian.Uint16(b)), true
+	}
+	takeChild := func() (PageID, bool) {
+		b, ok := take(diskChildSize)
+		if !ok {
+			return 0, false
+		}
+		return PageID(binary.BigEndian.Uint64(b)), true
+	}
+
+	prefix, ok := take(int(binary.BigEndian.Uint16(buf[20:])))
+	if !ok {
+		return nil, corrupt("prefix runs off the page")
+	}
+	if !n.leaf {
+		child, ok := takeChild()
+		if !ok {
+			return nil, corrupt("missing first child")
+		}
+		n.children = append(n.children, child)
+	}
+	for i := 0; i < count; i++ {
+		length, ok := takeLength()
+		suffix, ok2 := take(length)
+		if !ok || !ok2 {
+			return nil, corrupt(fmt.Sprintf("key %d runs off the page", i))
+		}
+		raw := append(bytes.Clone(prefix), suffix...)
+		key, err := keys.Decode(raw)
+		if err != nil {
+			return nil, corrupt(fmt.Sprintf("key %d: %v", i, err))
+		}
+		n.keys = append(n.keys, key)
+		n.raw = append(n.raw, raw)
+
+		if n.leaf {
+			length, ok := takeLength()
+			value, ok2 := take(length)
+			if !ok || !ok2 {
+				return nil, corrupt(fmt.Sprintf("value %d runs off the page", i))
+			}
+			n.values = append(n.values, bytes.Clone(value))
+		} else {
+			child, ok := takeChild()
+			if !ok {
+				return nil, corrupt(fmt.Sprintf("child %d runs off the page", i+1))
+			}
+			n.children = append(n.children, child)
+		}
+	}
+	return n, nil
+}
+
+// Separator is implemented by key codecs that can invent keys. Given lo < hi
+// it returns the shortest key s with lo < s <= hi. A leaf split then pushes s
+// up instead of the whole first key of the right leaf, which keeps internal
+// nodes small and their fan-out high
+type Separator[T any] interface {
+	Separator(lo, hi T) T
+}
+
+// Separator cuts 'hi' just past the first byte where it differs from 'lo'
+func (c StringCodec) Separator(lo, hi string) string {
+	i := 0
+	for i < len(lo) && i < len(hi) && lo[i] == hi[i] {
+		i++
+	}
+	if i >= len(hi) {
+		return hi
+	}
+	return hi[:i+1]
+}
@@ -0,0 +1,229 @@
+package DataStructures
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStringSeparator(t *testing.T) {
+	c := StringCodec{Max: 100}
+	for _, tc := range []struct{ lo, hi, want string }{
+		{"apple", "banana", "b"},
+		{"https://a.com/x1", "https://a.com/y0", "https://a.com/y"},
+		{"abc", "abcd", "abcd"},
+		{"", "a", "a"},
+		{"abc", "abd", "abd"},
+	} {
+		got := c.Separator(tc.lo, tc.hi)
+		if got != tc.want || got <= tc.lo || got > tc.hi {
+			t.Errorf("Separator(%q, %q) = %q, want %q", tc.lo, tc.hi, got, tc.want)
+		}
+	}
+}
+
+// pageStats walks a DiskBTree and reports the longest separator and the most
+// entries found in one leaf
+func pageStats[V any](t *testing.T, tree *DiskBTree[string, V]) (longestSep, fullestLeaf, height int) {
+	t.Helper()
+	level := []PageID{tree.root}
+	for len(level) > 0 {
+		height++
+		var below []PageID
+		for _, id := range level {
+			n, err := tree.load(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n.leaf {
+				fullestLeaf = max(fullestLeaf, len(n.keys))
+				continue
+			}
+			for _, key := range n.keys {
+				longestSep = max(longestSep, len(key))
+			}
+			below = append(below, n.children...)
+		}
+		level = below
+	}
+	return longestSep, fullestLeaf, height
+}
+
+func TestDiskBTreePrefixCompression(t *testing.T) {
+	r := rand.New(rand.NewSource(17))
+	for _, tc := range []struct {
+		name string
+		key  func(i int) string
+		// leaves must hold 'gain' times the entries that fit uncompressed
+		gain float64
+		// and separators must be shorter than this
+		longestSep int
+	}{
+		// Neighbours share all but the last few digits
+		{"sequential", func(i int) string { return fmt.Sprintf("https://example.com/catalog/items/%08d/reviews", i) }, 2, 100},
+		// Random ids part ways a few characters past the shared prefix,
+		// so separators stop there while the rest of the key is long
+		{"random", func(int) string {
+			return fmt.Sprintf("https://example.com/users/%016x/profile/settings/notifications", r.Uint64())
+		}, 1.2, 35},
+	} {
+		tree, err := OpenDiskBTree[string, uint64](filepath.Join(t.TempDir(), tc.name), 0, StringCodec{Max: 100}, Uint64Codec{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys := map[string]uint64{}
+		for i := 0; len(keys) < 5000; i++ {
+			key := tc.key(i)
+			keys[key] = uint64(i)
+			if err := tree.Put(key, uint64(i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tree.Validate(); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		for key, v := range keys {
+			if got, ok, err := tree.Get(key); err != nil || !ok || got != v {
+				t.Fatalf("%s: Get(%q) = (%d, %v, %v)", tc.name, key, got, ok, err)
+			}
+		}
+
+		longestSep, fullestLeaf, height := pageStats(t, tree)
+		keyLen := len(tc.key(0))
+		uncompressed := (DefaultPageSize - diskNodeHeader) / (2*diskLengthSize + keyLen + 8)
+		if float64(fullestLeaf) < tc.gain*float64(uncompressed) {
+			t.Errorf("%s: fullest leaf holds %d entries, %d fit without prefix compression", tc.name, fullestLeaf, uncompressed)
+		}
+		if longestSep >= tc.longestSep {
+			t.Errorf("%s: separators up to %d bytes long, keys are %d", tc.name, longestSep, keyLen)
+		}
+		if height > 2 {
+			t.Errorf("%s: 5000 keys took %d levels", tc.name, height)
+		}
+		tree.Close()
+	}
+}
+
+func TestDiskBTreeVariableValues(t *testing.T) {
+	tree, err := OpenDiskBTree[string, string](filepath.Join(t.TempDir(), "values"), 512, StringCodec{Max: 24}, StringCodec{Max: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+
+	r := rand.New(rand.NewSource(1))
+	want := map[string]string{}
+	for step := 0; step < 6000; step++ {
+		key := fmt.Sprintf("key/%04d", r.Intn(600))
+		if r.Intn(4) == 0 {
+			if _, found, err := tree.Delete(key); err != nil {
+				t.Fatal(err)
+			} else if _, ok := want[key]; found != ok {
+				t.Fatalf("Delete(%q) found %v", key, found)
+			}
+			delete(want, key)
+			continue
+		}
+		// Values grow and shrink in place, a leaf can overflow or underflow on an update
+		value := strings.Repeat("v", r.Intn(61))
+		if err := tree.Put(key, value); err != nil {
+			t.Fatal(err)
+		}
+		want[key] = value
+		if step%600 == 0 {
+			if err := tree.Validate(); err != nil {
+				t.Fatalf("step %d: %v", step, err)
+			}
+		}
+	}
+	if err := tree.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if tree.Len() != len(want) {
+		t.Fatalf("Len = %d, want %d", tree.Len(), len(want))
+	}
+	for key, value := range want {
+		if got, ok, err := tree.Get(key); err != nil || !ok || got != value {
+			t.Fatalf("Get(%q) = (%q, %v, %v), want %q", key, got, ok, err, value)
+		}
+	}
+	if err := tree.Put("k", strings.Repeat("v", 61)); !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("expected ErrValueTooLarge, got %v", err)
+	}
+}
+
+func TestDiskBTreeCorruptPage(t *testing.T) {
+	tree, err := OpenDiskBTree[string, uint64](filepath.Join(t.TempDir(), "corrupt"), 256, StringCodec{Max: 20}, Uint64Codec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	for i := 0; i < 100; i++ {
+		if err := tree.Put(fmt.Sprintf("key%03d", i), uint64(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	leaf, err := tree.findLeaf("key050")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := make([]byte, 256)
+	if err := tree.pager.Read(leaf.id, page); err != nil {
+		t.Fatal(err)
+	}
+	// A prefix that runs off the end of the page
+	page[20], page[21] = 0xFF, 0xFF
+	if err := tree.pager.Write(leaf.id, page); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := tree.Get("key050"); !errors.Is(err, ErrPagerCorrupted) {
+		t.Fatalf("expected ErrPagerCorrupted, got %v", err)
+	}
+	var verr *ValidationError
+	if err := tree.Validate(); !errors.As(err, &verr) {
+		t.Fatalf("Validate missed the corrupt page: %v", err)
+	}
+}
+
+func TestDiskBTreePrefixLoss(t *testing.T) {
+	tree, err := OpenDiskBTree[string, uint64](filepath.Join(t.TempDir(), "loss"), 256, StringCodec{Max: 40}, Uint64Codec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	prefix := strings.Repeat("p", 36)
+	for i := 0; i < 200; i++ {
+		if err := tree.Put(fmt.Sprintf("%s%03d", prefix, i), uint64(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, fullest, _ := pageStats(t, tree)
+	if fullest*(2*diskLengthSize+len(prefix)+3+8) <= 256 {
+		t.Fatalf("fullest leaf holds %d entries, they would fit uncompressed", fullest)
+	}
+
+	// A key without the prefix takes it away from a whole leaf, which then
+	// needs several pages
+	for _, key := range []string{"a", "z", prefix, prefix + "0", prefix + "9999"} {
+		if err := tree.Put(key, 1); err != nil {
+			t.Fatal(err)
+		}
+		if err := tree.Validate(); err != nil {
+			t.Fatalf("after Put(%q): %v", key, err)
+		}
+	}
+	if tree.Len() != 205 {
+		t.Fatalf("Len = %d", tree.Len())
+	}
+	for _, key := range []string{"a", "z"} {
+		if _, found, err := tree.Delete(key); err != nil || !found {
+			t.Fatalf("Delete(%q) = %v, %v", key, found, err)
+		}
+	}
+	if err := tree.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,252 @@
+package DataStructures
+
+import (
+	"errors"
+	"math/rand"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+)
+
+// Compile time checks that a DiskCursor works wherever an Iterator does, and
+// a DiskBTree wherever a DiskTree does through its adapter
+var (
+	_ Iterator[int64, int64] = (*DiskCursor[int64, int64])(nil)
+	_ DiskTree[int64, int64] = (*DiskTreeAdapter[int64, int64])(nil)
+)
+
+// diskKeys collects every key through the leaf chain, in both directions
+func diskKeys(t *testing.T, tree *DiskBTree[int64, int64]) []int64 {
+	t.Helper()
+	var forward, backward []int64
+	c := tree.First()
+	for ; c.Valid(); c.Next() {
+		forward = append(forward, c.Key())
+	}
+	if c.Err() != nil {
+		t.Fatal(c.Err())
+	}
+	for c := tree.Last(); c.Valid(); c.Prev() {
+		backward = append(backward, c.Key())
+	}
+	if len(forward) != len(backward) {
+		t.Fatalf("forward scan found %d keys, backward %d", len(forward), len(backward))
+	}
+	return forward
+}
+
+func TestDiskBTreeAgainstMap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index")
+	// 128 byte pages hold a handful of entries each
+	tree, err := OpenDiskBTree[int64, int64](path, 128, Int64Codec{}, Int64Codec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := rand.New(rand.NewSource(16))
+	want := map[int64]int64{}
+	for step := 0; step < 5000; step++ {
+		k := int64(r.Intn(800))
+		if r.Intn(3) == 0 {
+			old, found, err := tree.Delete(k)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v, ok := want[k]; found != ok || old != v {
+				t.Fatalf("Delete(%d) = (%d, %v), want (%d, %v)", k, old, found, v, ok)
+			}
+			delete(want, k)
+		} else {
+			if err := tree.Put(k, int64(step)); err != nil {
+				t.Fatal(err)
+			}
+			want[k] = int64(step)
+		}
+		if step%500 == 0 {
+			if err := tree.Validate(); err != nil {
+				t.Fatalf("step %d: %v", step, err)
+			}
+		}
+	}
+	if err := tree.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if tree.Len() != len(want) {
+		t.Fatalf("Len = %d, want %d", tree.Len(), len(want))
+	}
+	for k, v := range want {
+		if got, ok, err := tree.Get(k); err != nil || !ok || got != v {
+			t.Fatalf("Get(%d) = (%d, %v, %v), want %d", k, got, ok, err, v)
+		}
+	}
+
+	// Everything is still there after reopening the file
+	if err := tree.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tree, err = OpenDiskBTree[int64, int64](path, 0, Int64Codec{}, Int64Codec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	if err := tree.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	var keys []int64
+	for k := range want {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	got := diskKeys(t, tree)
+	if len(got) != len(keys) {
+		t.Fatalf("reopened tree holds %d keys, want %d", len(got), len(keys))
+	}
+	for i := range keys {
+		if got[i] != keys[i] {
+			t.Fatalf("key %d is %d, want %d", i, got[i], keys[i])
+		}
+	}
+
+	// Emptying the tree hands its pages back for reuse
+	pages := tree.pager.PageCount()
+	for _, k := range keys {
+		if _, found, err := tree.Delete(k); err != nil || !found {
+			t.Fatalf("Delete(%d) = %v, %v", k, found, err)
+		}
+	}
+	if err := tree.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for k := int64(0); k < 100; k++ {
+		if err := tree.Put(k, k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if tree.pager.PageCount() > pages {
+		t.Fatalf("file grew from %d to %d pages instead of reusing freed ones", pages, tree.pager.PageCount())
+	}
+}
+
+func TestDiskBTreeCursors(t *testing.T) {
+	tree, err := OpenDiskBTree[int64, int64](filepath.Join(t.TempDir(), "index"), 128, Int64Codec{}, Int64Codec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	if _, ok, err := tree.Min(); ok || err != nil {
+		t.Fatalf("empty tree has a minimum: %v %v", ok, err)
+	}
+	if c := tree.Seek(5); c.Valid() {
+		t.Fatal("cursor valid in an empty tree")
+	}
+	for k := int64(0); k < 200; k += 2 {
+		if err := tree.Put(k, k*10); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if c := tree.Seek(51); !c.Valid() || c.Key() != 52 || c.Value() != 520 {
+		t.Fatal("Seek(51) did not land on 52")
+	}
+	if c := tree.SeekLast(51); !c.Valid() || c.Key() != 50 {
+		t.Fatal("SeekLast(51) did not land on 50")
+	}
+	if c := tree.Seek(199); c.Valid() {
+		t.Fatal("Seek past the end is valid")
+	}
+	if c := tree.SeekLast(-1); c.Valid() {
+		t.Fatal("SeekLast before the start is valid")
+	}
+
+	var got []int64
+	for c := tree.Range(10, 20, false, true); c.Valid(); c.Next() {
+		got = append(got, c.Key())
+	}
+	if len(got) != 5 || got[0] != 12 || got[4] != 20 {
+		t.Fatalf("Range(10, 20] = %v", got)
+	}
+	if min, _, _ := tree.Min(); min != 0 {
+		t.Errorf("Min = %d", min)
+	}
+	if max, _, _ := tree.Max(); max != 198 {
+		t.Errorf("Max = %d", max)
+	}
+}
+
+func TestDiskBTreeStringKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index")
+	tree, err := OpenDiskBTree[string, uint64](path, 0, StringCodec{Max: 30}, Uint64Codec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	words := []string{"pear", "apple", "fig", "", "banana", "kiwi"}
+	for i, w := range words {
+		if err := tree.Put(w, uint64(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tree.Put("a string far longer than thirty bytes", 1); !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("expected ErrValueTooLarge, got %v", err)
+	}
+	if tree.Len() != len(words) {
+		t.Fatalf("Len = %d after a rejected Put", tree.Len())
+	}
+	if err := tree.Insert("fig"); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok, _ := tree.Get("fig"); !ok || v != 2 {
+		t.Fatalf("Insert replaced an existing value: %d", v)
+	}
+	tree.Close()
+
+	if _, err := OpenDiskBTree[string, uint64](path, 0, StringCodec{Max: 20}, Uint64Codec{}); !errors.Is(err, ErrDiskLayout) {
+		t.Fatalf("expected ErrDiskLayout, got %v", err)
+	}
+	if _, err := OpenDiskBTree[int64, int64](filepath.Join(t.TempDir(), "small"), 64, Int64Codec{}, Int64Codec{}); !errors.Is(err, ErrPageTooSmall) {
+		t.Fatalf("expected ErrPageTooSmall, got %v", err)
+	}
+}
+
+func TestDiskTreeAdapter(t *testing.T) {
+	disk, err := OpenDiskBTree[int64, int64](filepath.Join(t.TempDir(), "index"), 128, Int64Codec{}, Int64Codec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	adapter := disk.AsDiskTree()
+
+	// The same calls give the same answers on both trees
+	for _, tree := range []DiskTree[int64, int64]{newBTree[int64, int64](4), adapter} {
+		for k := int64(0); k < 100; k++ {
+			tree.Put(k, k*k)
+		}
+		tree.Insert(5)
+		if v, ok := tree.Get(5); !ok || v != 25 {
+			t.Errorf("%T: Get(5) = (%d, %v) after Insert of an existing key", tree, v, ok)
+		}
+		if v, ok := tree.Delete(10); !ok || v != 100 || tree.Search(10) {
+			t.Errorf("%T: Delete(10) = (%d, %v)", tree, v, ok)
+		}
+		if k, _ := tree.Max(); k != 99 {
+			t.Errorf("%T: Max = %d", tree, k)
+		}
+		var got []int64
+		for c := tree.Range(8, 12, true, false); c.Valid(); c.Next() {
+			got = append(got, c.Key())
+		}
+		if !slices.Equal(got, []int64{8, 9, 11}) {
+			t.Errorf("%T: Range[8, 12) = %v", tree, got)
+		}
+	}
+	adapter.Display()
+	if err := adapter.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Errors are kept instead of lost
+	disk.Close()
+	adapter.Put(1000, 1)
+	if adapter.Err() == nil {
+		t.Fatal("a Put on a closed tree should leave an error behind")
+	}
+}
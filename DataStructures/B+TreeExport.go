@@ -0,0 +1,247 @@
+package DataStructures
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var ErrMalformedTree = errors.New("btree: malformed tree description")
+
+// WriteDOT writes the tree as a Graphviz digraph: one record per node with a
+// port for every child edge, solid child edges, and dashed 'next' and dotted
+// 'prev' edges along the leaves. Like Display it needs the tree to itself
+func (b *BTree[K, V]) WriteDOT(w io.Writer) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "digraph btree {")
+	fmt.Fprintln(out, "\tnode [shape=record];")
+	nodes, ids := b.numberNodes()
+	var leaves []string
+	for id, node := range nodes {
+		var fields []string
+		for i, key := range node.keys {
+			if !node.leaf {
+				fields = append(fields, fmt.Sprintf("<c%d> ", i))
+			}
+			fields = append(fields, dotEscape(fmt.Sprintf("%v", key)))
+		}
+		if !node.leaf {
+			fields = append(fields, fmt.Sprintf("<c%d> ", len(node.keys)))
+		}
+		fmt.Fprintf(out, "\tn%d [label=\"%s\"];\n", id, strings.Join(fields, "|"))
+		if node.leaf {
+			leaves = append(leaves, fmt.Sprintf("n%d", id))
+		}
+	}
+	for id, node := range nodes {
+		for i, child := range node.children {
+			fmt.Fprintf(out, "\tn%d:c%d -> n%d;\n", id, i, ids[child])
+		}
+		if next, ok := ids[node.next]; ok {
+			fmt.Fprintf(out, "\tn%d -> n%d [style=dashed, color=blue, constraint=false];\n", id, next)
+		}
+		if prev, ok := ids[node.prev]; ok {
+			fmt.Fprintf(out, "\tn%d -> n%d [style=dotted, color=gray, constraint=false];\n", id, prev)
+		}
+	}
+	if len(leaves) > 0 {
+		fmt.Fprintf(out, "\t{ rank=same; %s; }\n", strings.Join(leaves, "; "))
+	}
+	fmt.Fprintln(out, "}")
+	return out.Flush()
+}
+
+// dotEscape protects the characters that mean something inside a record label
+func dotEscape(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`|{}<>"\ `, r) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// numberNodes lists the nodes in level order and gives each its index as an id
+func (b *BTree[K, V]) numberNodes() ([]*bNode[K, V], map[*bNode[K, V]]int) {
+	ids := map[*bNode[K, V]]int{}
+	if b.root == nil {
+		return nil, ids
+	}
+	nodes := []*bNode[K, V]{b.root}
+	for i := 0; i < len(nodes); i++ {
+		ids[nodes[i]] = i
+		nodes = append(nodes, nodes[i].children...)
+	}
+	return nodes, ids
+}
+
+// jsonTree is the WriteJSON form of a tree, nodes[0] is the root
+type jsonTree[K any, V any] struct {
+	Degree  uint16           `json:"degree"`
+	Counted bool             `json:"counted,omitempty"`
+	Nodes   []jsonNode[K, V] `json:"nodes"`
+}
+
+type jsonNode[K any, V any] struct {
+	ID       int   `json:"id"`
+	Leaf     bool  `json:"leaf"`
+	Keys     []K   `json:"keys"`
+	Values   []V   `json:"values,omitempty"`
+	Children []int `json:"children,omitempty"`
+	Counts   []int `json:"counts,omitempty"`
+	Next     *int  `json:"next,omitempty"`
+	Prev     *int  `json:"prev,omitempty"`
+}
+
+// WriteJSON writes every node with its keys, values, child ids and leaf links.
+// The links are written as they are, not as they should be, so a broken tree
+// can be saved and loaded back with ReadBTreeJSON to reproduce a bug
+func (b *BTree[K, V]) WriteJSON(w io.Writer) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	nodes, ids := b.numberNodes()
+	tree := jsonTree[K, V]{Degree: b.degree, Counted: b.counted, Nodes: make([]jsonNode[K, V], len(nodes))}
+	link := func(n *bNode[K, V]) *int {
+		if id, ok := ids[n]; ok {
+			return &id
+		}
+		return nil
+	}
+	for id, node := range nodes {
+		out := jsonNode[K, V]{ID: id, Leaf: node.leaf, Keys: node.keys}
+		if node.leaf {
+			out.Values = node.values
+			out.Next, out.Prev = link(node.next), link(node.prev)
+		} else {
+			out.Counts = node.counts
+			for _, child := range node.children {
+				out.Children = append(out.Children, ids[child])
+			}
+		}
+		tree.Nodes[id] = out
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(tree)
+}
+
+// ReadBTreeJSON loads a tree written by WriteJSON, node for node. A tree that
+// says it is counted, or has counts on any node, keeps counts, and the missing
+// ones are worked out from the children. The result is not validated, so a
+// captured broken tree stays broken, call Validate to check it
+func ReadBTreeJSON[K Ordered, V any](r io.Reader) (*BTree[K, V], error) {
+	return ReadBTreeJSONFunc[K, V](r, cmp.Compare[K])
+}
+
+// ReadBTreeJSONFunc is ReadBTreeJSON for a tree ordered by 'compare', see newBTreeFunc
+func ReadBTreeJSONFunc[K any, V any](r io.Reader, compare func(a, b K) int) (*BTree[K, V], error) {
+	var tree jsonTree[K, V]
+	if err := json.NewDecoder(r).Decode(&tree); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedTree, err)
+	}
+	if tree.Degree < 3 {
+		return nil, fmt.Errorf("%w: degree %d", ErrMalformedTree, tree.Degree)
+	}
+
+	b := &BTree[K, V]{degree: tree.Degree, compare: compare, counted: tree.Counted}
+	if len(tree.Nodes) == 0 {
+		return b, nil
+	}
+	nodes := make([]*bNode[K, V], len(tree.Nodes))
+	for i, in := range tree.Nodes {
+		if in.ID != i {
+			return nil, fmt.Errorf("%w: node %d has id %d", ErrMalformedTree, i, in.ID)
+		}
+		nodes[i] = b.newNode(in.Leaf)
+		nodes[i].keys = append(nodes[i].keys, in.Keys...)
+	}
+
+	lookup := func(id *int) (*bNode[K, V], error) {
+		if id == nil {
+			return nil, nil
+		}
+		if *id < 0 || *id >= len(nodes) {
+			return nil, fmt.Errorf("%w: no node %d", ErrMalformedTree, *id)
+		}
+		return nodes[*id], nil
+	}
+	for i, in := range tree.Nodes {
+		node := nodes[i]
+		if in.Leaf {
+			if len(in.Children) != 0 {
+				return nil, fmt.Errorf("%w: leaf %d has children", ErrMalformedTree, i)
+			}
+			if len(in.Values) != len(in.Keys) {
+				return nil, fmt.Errorf("%w: leaf %d has %d keys but %d values", ErrMalformedTree, i, len(in.Keys), len(in.Values))
+			}
+			node.values = append(node.values, in.Values...)
+			var err error
+			if node.next, err = lookup(in.Next); err != nil {
+				return nil, err
+			}
+			if node.prev, err = lookup(in.Prev); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if len(in.Children) == 0 {
+			return nil, fmt.Errorf("%w: internal node %d has no children", ErrMalformedTree, i)
+		}
+		for _, id := range in.Children {
+			id := id
+			child, err := lookup(&id)
+			if err != nil {
+				return nil, err
+			}
+			// Every node but the root hangs under exactly one parent, which keeps
+			// the walks below from looping
+			if id == 0 || child.parent != nil {
+				return nil, fmt.Errorf("%w: node %d has more than one parent", ErrMalformedTree, id)
+			}
+			child.parent = node
+			node.children = append(node.children, child)
+		}
+		if in.Counts != nil {
+			if len(in.Counts) != len(in.Children) {
+				return nil, fmt.Errorf("%w: node %d has %d children but %d counts", ErrMalformedTree, i, len(in.Children), len(in.Counts))
+			}
+			node.counts = append(node.counts, in.Counts...)
+			b.counted = true
+		}
+	}
+
+	b.root = nodes[0]
+	if b.counted {
+		fillCounts(b.root)
+	}
+	return b, nil
+}
+
+// fillCounts works out the missing subtree counts below 'node' and returns its size
+func fillCounts[K any, V any](node *bNode[K, V]) int {
+	if node.leaf {
+		return len(node.keys)
+	}
+	missing := len(node.counts) == 0
+	total := 0
+	for i, child := range node.children {
+		size := fillCounts(child)
+		if missing {
+			node.counts = append(node.counts, size)
+		}
+		total += node.counts[i]
+	}
+	return total
+}
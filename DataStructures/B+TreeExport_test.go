@@ -0,0 +1,178 @@
+package DataStructures
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestWriteJSONRoundTrip(t *testing.T) {
+	btree := newBTree[int, string](4)
+	for k := 0; k < 60; k++ {
+		btree.Put(k*3, strings.Repeat("v", k%4))
+	}
+	for k := 0; k < 60; k += 7 {
+		btree.Delete(k * 3)
+	}
+
+	var first bytes.Buffer
+	if err := btree.WriteJSON(&first); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadBTreeJSON[int, string](bytes.NewReader(first.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Validate(); err != nil {
+		t.Fatalf("loaded tree: %v", err)
+	}
+	var second bytes.Buffer
+	if err := loaded.WriteJSON(&second); err != nil {
+		t.Fatal(err)
+	}
+	if first.String() != second.String() {
+		t.Fatalf("round trip changed the tree:\n%s\nvs\n%s", first.String(), second.String())
+	}
+	for c := btree.First(); c.Valid(); c.Next() {
+		if v, ok := loaded.Get(c.Key()); !ok || v != c.Value() {
+			t.Fatalf("loaded Get(%d) = (%q, %v), want %q", c.Key(), v, ok, c.Value())
+		}
+	}
+
+	// The loaded tree is a working tree
+	loaded.Put(1000, "x")
+	loaded.Delete(3)
+	if err := loaded.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// versionKey is a key outside Ordered that the JSON and gob encoders can both
+// write, newest version of a name first
+type versionKey struct {
+	Name    string
+	Version int
+}
+
+func compareVersionKey(a, b versionKey) int {
+	if c := cmp.Compare(a.Name, b.Name); c != 0 {
+		return c
+	}
+	return cmp.Compare(b.Version, a.Version)
+}
+
+// versionTree fills a comparator-ordered tree for the round trip tests
+func versionTree() *BTree[versionKey, int] {
+	btree := newBTreeFunc[versionKey, int](4, compareVersionKey)
+	for i := 0; i < 90; i++ {
+		btree.Put(versionKey{Name: fmt.Sprint("doc", i%7), Version: i}, i)
+	}
+	return btree
+}
+
+func TestWriteJSONRoundTripComparator(t *testing.T) {
+	btree := versionTree()
+	var buf bytes.Buffer
+	if err := btree.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadBTreeJSONFunc[versionKey, int](&buf, compareVersionKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Validate(); err != nil {
+		t.Fatalf("loaded tree: %v", err)
+	}
+	for c := btree.First(); c.Valid(); c.Next() {
+		if v, ok := loaded.Get(c.Key()); !ok || v != c.Value() {
+			t.Fatalf("loaded Get(%v) = (%d, %v), want %d", c.Key(), v, ok, c.Value())
+		}
+	}
+	// Newer versions still sort first after loading
+	loaded.Put(versionKey{Name: "doc0", Version: 1000}, -1)
+	if c := loaded.Seek(versionKey{Name: "doc0", Version: math.MaxInt}); !c.Valid() || c.Value() != -1 {
+		t.Errorf("Seek(doc0) = %v, want the newest version", c.Key())
+	}
+}
+
+// TestReadBTreeJSONFixture loads a hand written broken tree, the separator 5
+// does not bound the key 7 on its left, and counts are left out of a counted tree
+func TestReadBTreeJSONFixture(t *testing.T) {
+	fixture := `{
+		"degree": 3,
+		"counted": true,
+		"nodes": [
+			{"id": 0, "leaf": false, "keys": [5], "children": [1, 2]},
+			{"id": 1, "leaf": true, "keys": [1, 7], "values": [10, 70], "next": 2},
+			{"id": 2, "leaf": true, "keys": [8], "values": [80], "prev": 1}
+		]
+	}`
+	btree, err := ReadBTreeJSON[int, int](strings.NewReader(fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var verr *ValidationError
+	if err := btree.Validate(); !errors.As(err, &verr) || verr.Rule != "separator bound" {
+		t.Fatalf("expected a separator bound violation, got %v", err)
+	}
+	if got := btree.root.counts; !equalKeys(got, []int{2, 1}) {
+		t.Fatalf("counts = %v, want [2 1]", got)
+	}
+}
+
+func TestReadBTreeJSONErrors(t *testing.T) {
+	for name, input := range map[string]string{
+		"not json":        `{"degree": 3, "nodes": [`,
+		"small degree":    `{"degree": 1, "nodes": []}`,
+		"bad id":          `{"degree": 3, "nodes": [{"id": 4, "leaf": true, "keys": []}]}`,
+		"missing child":   `{"degree": 3, "nodes": [{"id": 0, "leaf": false, "keys": [1], "children": [1, 9]}, {"id": 1, "leaf": true, "keys": []}]}`,
+		"shared child":    `{"degree": 3, "nodes": [{"id": 0, "leaf": false, "keys": [1], "children": [1, 1]}, {"id": 1, "leaf": true, "keys": []}]}`,
+		"root as child":   `{"degree": 3, "nodes": [{"id": 0, "leaf": false, "keys": [1], "children": [0, 1]}, {"id": 1, "leaf": true, "keys": []}]}`,
+		"leaf children":   `{"degree": 3, "nodes": [{"id": 0, "leaf": true, "keys": [], "children": [1]}, {"id": 1, "leaf": true, "keys": []}]}`,
+		"no children":     `{"degree": 3, "nodes": [{"id": 0, "leaf": false, "keys": []}]}`,
+		"bad next":        `{"degree": 3, "nodes": [{"id": 0, "leaf": true, "keys": [], "next": 3}]}`,
+		"counts mismatch": `{"degree": 3, "nodes": [{"id": 0, "leaf": false, "keys": [1], "children": [1, 2], "counts": [1]}, {"id": 1, "leaf": true, "keys": [0], "values": [0]}, {"id": 2, "leaf": true, "keys": [1], "values": [1]}]}`,
+		"missing values":  `{"degree": 3, "nodes": [{"id": 0, "leaf": true, "keys": [1, 2], "values": [10]}]}`,
+		"extra values":    `{"degree": 3, "nodes": [{"id": 0, "leaf": true, "keys": [1], "values": [10, 20]}]}`,
+	} {
+		if _, err := ReadBTreeJSON[int, int](strings.NewReader(input)); !errors.Is(err, ErrMalformedTree) {
+			t.Errorf("%s: expected ErrMalformedTree, got %v", name, err)
+		}
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	btree := newBTree[string, int](3)
+	for _, k := range []string{"a", "b", "c", "d", "e", "f", "g", "with space", "x|y"} {
+		btree.Insert(k)
+	}
+	var out bytes.Buffer
+	if err := btree.WriteDOT(&out); err != nil {
+		t.Fatal(err)
+	}
+	dot := out.String()
+	stats := btree.Stats()
+	nodes := 0
+	for _, n := range stats.NodesPerLevel {
+		nodes += n
+	}
+	if !strings.HasPrefix(dot, "digraph btree {") || !strings.HasSuffix(dot, "}\n") {
+		t.Fatalf("not a digraph:\n%s", dot)
+	}
+	if got := strings.Count(dot, ":c"); got != nodes-1 {
+		t.Errorf("%d child edges, want %d", got, nodes-1)
+	}
+	if got := strings.Count(dot, "style=dashed"); got != stats.Leaves-1 {
+		t.Errorf("%d next edges, want %d", got, stats.Leaves-1)
+	}
+	if got := strings.Count(dot, "style=dotted"); got != stats.Leaves-1 {
+		t.Errorf("%d prev edges, want %d", got, stats.Leaves-1)
+	}
+	if !strings.Contains(dot, `with\ space`) || !strings.Contains(dot, `x\|y`) {
+		t.Errorf("record labels are not escaped:\n%s", dot)
+	}
+}
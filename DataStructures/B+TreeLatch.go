@@ -0,0 +1,246 @@
+package DataStructures
+
+/*
+Latch crabbing for concurrent BTree access.
+
+Readers take read latches top-down and let go of a parent as soon as the
+child is latched. Writers take write latches top-down and let go of every
+ancestor once they reach a node that is safe, meaning the operation cannot
+split or merge it, so any split or merge that does happen stays inside the
+latches they still hold. The root pointer has its own latch above the root,
+which a writer keeps only while the root itself might split or shrink.
+
+Trees created with subtree counts (see newCountedBTree) cannot let go that
+early: a write that adds or removes a key changes a count in every node from
+the root down. Their writers come in two kinds instead:
+
+  - crabValue: the key is already in place (or missing for a delete) and only
+    the leaf's values can change. The writer crabs down like a reader, with
+    write latches, and ends up holding just the leaf.
+  - crabCount: a key is added or removed. The writer keeps the root latch and
+    every node on the path until it is done, so splits, merges and count
+    updates all happen under latches it holds.
+
+A writer does not know which kind it is until it reaches the leaf, so it goes
+down as crabValue first and starts over as crabCount when the key count will
+change. In a counted tree structure and counts therefore only ever change
+under the root latch, which is what lets Rank and friends read a consistent
+set of counts.
+
+Nodes shared with a Snapshot have to be copied before they change, and a
+copied leaf relinks the leaf on its left, a sideways latch against the usual
+order. In a counted tree that happens on a crabCount path, where no other
+writer can be splitting or merging. Elsewhere a write that may copy gives up
+its latches and starts over with 'mu' held exclusively, see withPath. After
+a Snapshot every leaf is copied at most once, so this stays rare.
+
+Lock order is always parent before child. Sideways latches are only taken on
+nodes whose parent is held (siblings while rebalancing), or on the right
+neighbour of a leaf whose 'prev' pointer has to be fixed. Cursors never hold
+a latch between calls, see B+TreeCursor.go.
+*/
+
+// crabMode tells the descent what kind of change the writer is about to make
+type crabMode int
+
+const (
+	crabInsert crabMode = iota
+	crabDelete
+	crabValue // counted trees: only values in the leaf change
+	crabCount // a key is inserted or removed with counts to keep, or nodes are copied: the whole path changes
+)
+
+// safeFor reports whether 'n' can absorb a 'mode' change below it without the
+// latches above it
+func (n *bNode[K, V]) safeFor(mode crabMode, degree uint16, isRoot bool) bool {
+	switch mode {
+	case crabValue:
+		return true
+	case crabCount:
+		return false
+	case crabInsert:
+		return len(n.keys) < int(degree)-1
+	}
+	if isRoot {
+		// The root only changes once its last separator is merged away
+		return n.leaf || len(n.keys) > 1
+	}
+	return len(n.keys) > minKeys(degree)
+}
+
+// latchPath holds the write latches a writer still owns after crabbing down
+type latchPath[K any, V any] struct {
+	tree     *BTree[K, V]
+	rootHeld bool           // tree.rootLatch is write latched
+	nodes    []*bNode[K, V] // write latched nodes, top-down, the leaf last
+}
+
+// lockPath write latches the path from the root to the leaf that owns 'key',
+// releasing ancestors whenever it passes a node that is safe for 'mode'.
+// If the tree has no root only the root latch is held
+func (b *BTree[K, V]) lockPath(key K, mode crabMode) *latchPath[K, V] {
+	path := &latchPath[K, V]{tree: b, rootHeld: true}
+	b.rootLatch.Lock()
+	node := b.root
+	if node == nil {
+		return path
+	}
+
+	node.latch.Lock()
+	isRoot := true
+	for {
+		if node.safeFor(mode, b.degree, isRoot) {
+			path.releaseAncestors()
+		}
+		path.nodes = append(path.nodes, node)
+		if node.leaf {
+			if mode == crabCount {
+				path.own()
+			}
+			return path
+		}
+		child := node.children[b.childIndex(node, key)]
+		child.latch.Lock()
+		node = child
+		isRoot = false
+	}
+}
+
+// withPath runs 'write' on the latched path for a 'mode' write on 'key', with
+// 'mu' held shared. 'changesCount' says, given whether the key is in the tree,
+// if the write adds or removes a key, see lockWrite. A write that has to copy
+// nodes shared with a Snapshot and cannot do so on its own path is run again
+// with 'mu' held exclusively, on the whole path
+func (b *BTree[K, V]) withPath(key K, mode crabMode, changesCount func(exists bool) bool, write func(*latchPath[K, V])) {
+	b.mu.RLock()
+	if path := b.lockWrite(key, mode, changesCount); path != nil {
+		defer b.mu.RUnlock()
+		defer path.release()
+		write(path)
+		return
+	}
+	b.mu.RUnlock()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	path := b.lockPath(key, crabCount)
+	defer path.release()
+	write(path)
+}
+
+// lockWrite latches the path for a 'mode' writer on 'key'. In a counted tree
+// the value-only path is given up for a crabCount one when 'changesCount'
+// holds. The caller must look the key up again, it may have come or gone in
+// between. It returns nil, holding nothing, for a write in a tree without
+// counts that may copy shared nodes
+func (b *BTree[K, V]) lockWrite(key K, mode crabMode, changesCount func(exists bool) bool) *latchPath[K, V] {
+	if !b.counted {
+		path := b.lockPath(key, mode)
+		if path.leaf() == nil || !path.copies(mode) {
+			return path
+		}
+		path.release()
+		return nil
+	}
+
+	path := b.lockPath(key, crabValue)
+	if path.leaf() == nil {
+		// Empty tree, the root latch is still held for initRoot
+		return path
+	}
+	// A leaf shared with a Snapshot has to be copied, which changes its parent too
+	if exists, _ := b.leafIndex(path.leaf(), key); !changesCount(exists) && path.leaf().gen == b.gen {
+		return path
+	}
+	path.release()
+	return b.lockPath(key, crabCount)
+}
+
+// copies reports whether a 'mode' write on the path may have to copy a node
+// shared with a Snapshot: the leaf itself or, when keys can be removed, any
+// sibling rebalancing could borrow from or merge with, which are children of
+// the latched internal nodes. A node that is not shared never has a shared
+// ancestor, so the latched nodes above the leaf need no check
+func (p *latchPath[K, V]) copies(mode crabMode) bool {
+	gen := p.tree.gen
+	if p.leaf().gen != gen {
+		return true
+	}
+	if mode == crabInsert {
+		return false
+	}
+	for _, node := range p.nodes[:len(p.nodes)-1] {
+		for _, child := range node.children {
+			if child.gen != gen {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// adjustCounts adds 'delta' to the subtree count of every child on a crabCount
+// path. Trees without counts have nothing to adjust
+func (p *latchPath[K, V]) adjustCounts(delta int) {
+	if !p.tree.counted {
+		return
+	}
+	for i := 0; i+1 < len(p.nodes); i++ {
+		parent := p.nodes[i]
+		parent.counts[findChildIndex(parent, p.nodes[i+1])] += delta
+	}
+}
+
+// leaf returns the latched leaf at the bottom of the path, or nil for an empty tree
+func (p *latchPath[K, V]) leaf() *bNode[K, V] {
+	if len(p.nodes) == 0 {
+		return nil
+	}
+	return p.nodes[len(p.nodes)-1]
+}
+
+// releaseAncestors drops every latch held so far, including the root latch
+func (p *latchPath[K, V]) releaseAncestors() {
+	for _, node := range p.nodes {
+		node.latch.Unlock()
+	}
+	p.nodes = p.nodes[:0]
+	if p.rootHeld {
+		p.tree.rootLatch.Unlock()
+		p.rootHeld = false
+	}
+}
+
+// release drops every latch still held once the operation is done
+func (p *latchPath[K, V]) release() {
+	for i := len(p.nodes) - 1; i >= 0; i-- {
+		p.nodes[i].latch.Unlock()
+	}
+	p.nodes = nil
+	if p.rootHeld {
+		p.tree.rootLatch.Unlock()
+		p.rootHeld = false
+	}
+}
+
+// readLeaf descends to a leaf with read latch crabbing, taking the child
+// returned by 'pick' at every internal node. The leaf comes back read latched
+// and the caller must RUnlock it. It returns nil if the tree has no root
+func (b *BTree[K, V]) readLeaf(pick func(*bNode[K, V]) int) *bNode[K, V] {
+	b.rootLatch.RLock()
+	node := b.root
+	if node == nil {
+		b.rootLatch.RUnlock()
+		return nil
+	}
+	node.latch.RLock()
+	b.rootLatch.RUnlock()
+
+	for !node.leaf {
+		child := node.children[pick(node)]
+		child.latch.RLock()
+		node.latch.RUnlock()
+		node = child
+	}
+	return node
+}
@@ -0,0 +1,287 @@
+package DataStructures
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Stable keys are multiples of 10 and are never touched by the writers below,
+// so every reader and scanner must always see all of them
+const (
+	stableKeys   = 200
+	stableStride = 10
+)
+
+func stableTree(degree uint16) *BTree[int, int] {
+	return fillStable(newBTree[int, int](degree))
+}
+
+// fillStable puts the stable keys into 'btree'
+func fillStable(btree *BTree[int, int]) *BTree[int, int] {
+	for i := 0; i < stableKeys; i++ {
+		btree.Put(i*stableStride, i)
+	}
+	return btree
+}
+
+// runWriters churns keys 10*i+1+w for each writer 'w' and returns what each writer expects to remain
+func runWriters(btree *BTree[int, int], writers, ops int, wg *sync.WaitGroup) []map[int]int {
+	expected := make([]map[int]int, writers)
+	for w := 0; w < writers; w++ {
+		expected[w] = make(map[int]int)
+		wg.Add(1)
+		go func(w int, mine map[int]int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(w)))
+			for op := 0; op < ops; op++ {
+				k := rng.Intn(stableKeys)*stableStride + 1 + w
+				if rng.Intn(3) == 0 {
+					btree.Delete(k)
+					delete(mine, k)
+				} else {
+					btree.Put(k, op)
+					mine[k] = op
+				}
+			}
+		}(w, expected[w])
+	}
+	return expected
+}
+
+func TestConcurrentReadersAndWriters(t *testing.T) {
+	for _, degree := range []uint16{3, 4, 7} {
+		btree := stableTree(degree)
+		var wg sync.WaitGroup
+		expected := runWriters(btree, 6, 3000, &wg)
+
+		errs := make(chan string, 16)
+		for r := 0; r < 4; r++ {
+			wg.Add(1)
+			go func(r int) {
+				defer wg.Done()
+				rng := rand.New(rand.NewSource(int64(100 + r)))
+				for i := 0; i < 4000; i++ {
+					i := rng.Intn(stableKeys)
+					if v, ok := btree.Get(i * stableStride); !ok || v != i {
+						errs <- "stable key missing from Get"
+						return
+					}
+					btree.Search(rng.Intn(stableKeys * stableStride))
+				}
+			}(r)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Fatalf("degree %d: %s", degree, err)
+		}
+
+		if err := btree.Validate(); err != nil {
+			t.Fatalf("degree %d: tree broken after concurrent use: %v", degree, err)
+		}
+		for w, mine := range expected {
+			for k, v := range mine {
+				if got, ok := btree.Get(k); !ok || got != v {
+					t.Fatalf("degree %d: writer %d lost key %d", degree, w, k)
+				}
+			}
+		}
+		if got := len(leafKeys(btree)); got != stableKeys+countEntries(expected) {
+			t.Fatalf("degree %d: tree holds %d keys, expected %d", degree, got, stableKeys+countEntries(expected))
+		}
+	}
+}
+
+func countEntries(maps []map[int]int) int {
+	total := 0
+	for _, m := range maps {
+		total += len(m)
+	}
+	return total
+}
+
+func TestConcurrentRangeScans(t *testing.T) {
+	btree := stableTree(4)
+	var wg sync.WaitGroup
+	runWriters(btree, 4, 3000, &wg)
+
+	errs := make(chan string, 16)
+	scan := func(reverse bool, seed int64) {
+		defer wg.Done()
+		rng := rand.New(rand.NewSource(seed))
+		for i := 0; i < 150; i++ {
+			lo := rng.Intn(stableKeys/2) * stableStride
+			hi := lo + rng.Intn(stableKeys/2)*stableStride
+			var it Iterator[int, int]
+			if reverse {
+				it = btree.ReverseRange(lo, hi, true, true)
+			} else {
+				it = btree.Range(lo, hi, true, true)
+			}
+
+			stable, prev, first := 0, 0, true
+			for it.Valid() {
+				k := it.Key()
+				if k < lo || k > hi {
+					errs <- "scan returned a key outside its bounds"
+					return
+				}
+				if !first && ((!reverse && k <= prev) || (reverse && k >= prev)) {
+					errs <- "scan returned keys out of order or twice"
+					return
+				}
+				if k%stableStride == 0 {
+					stable++
+				}
+				prev, first = k, false
+				if reverse {
+					it.Prev()
+				} else {
+					it.Next()
+				}
+			}
+			if want := (hi-lo)/stableStride + 1; stable != want {
+				errs <- "scan missed a key that was present for the whole scan"
+				return
+			}
+		}
+	}
+	for s := 0; s < 3; s++ {
+		wg.Add(2)
+		go scan(false, int64(s))
+		go scan(true, int64(s))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+	if err := btree.Validate(); err != nil {
+		t.Fatalf("tree broken after concurrent scans: %v", err)
+	}
+}
+
+func TestConcurrentInsertIntoEmptyTree(t *testing.T) {
+	// Every writer races to create and then split the very first root
+	btree := newBTree[int, int](3)
+	btree.root = nil
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				btree.Insert(i*8 + w)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if err := btree.Validate(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if got := len(leafKeys(btree)); got != 1600 {
+		t.Fatalf("Expected 1600 keys, got %d", got)
+	}
+
+	// And all of them race to empty it again
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				if _, ok := btree.Delete(i*8 + w); !ok {
+					t.Errorf("Delete(%d) did not find the key", i*8+w)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	if err := btree.Validate(); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if _, ok := btree.Min(); ok {
+		t.Fatalf("Expected an empty tree")
+	}
+}
+
+// TestConcurrentInsertUnique races writers over the same keys, exactly one
+// InsertUnique per key may succeed
+func TestConcurrentInsertUnique(t *testing.T) {
+	btree := newBTree[int, int](4)
+	const keys, writers = 500, 8
+	wins := make([][]int, writers)
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for _, k := range rand.New(rand.NewSource(int64(w))).Perm(keys) {
+				switch err := btree.InsertUnique(k); {
+				case err == nil:
+					wins[w] = append(wins[w], k)
+				case !errors.Is(err, ErrDuplicateKey):
+					t.Errorf("InsertUnique(%d) = %v", k, err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	seen := map[int]bool{}
+	for _, won := range wins {
+		for _, k := range won {
+			if seen[k] {
+				t.Fatalf("key %d was inserted twice", k)
+			}
+			seen[k] = true
+		}
+	}
+	if len(seen) != keys {
+		t.Fatalf("%d keys were inserted, want %d", len(seen), keys)
+	}
+	if err := btree.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestInsertsIntoSeparateLeavesRunTogether holds the path of an insert into a
+// leaf with room and checks an insert into another leaf gets through meanwhile
+func TestInsertsIntoSeparateLeavesRunTogether(t *testing.T) {
+	btree := newBTree[int, int](8)
+	for k := 0; k < 1000; k += 10 {
+		btree.Put(k, k)
+	}
+
+	btree.mu.RLock()
+	path := btree.lockWrite(1, crabInsert, func(exists bool) bool { return !exists })
+	if path.rootHeld || len(path.nodes) != 1 {
+		path.release()
+		btree.mu.RUnlock()
+		t.Fatalf("insert into a leaf with room holds the root latch: %v, and %d nodes", path.rootHeld, len(path.nodes))
+	}
+	if path.leaf() == btree.frozenLeaf(func(n *bNode[int, int]) int { return btree.childIndex(n, 991) }) {
+		t.Fatal("test needs the keys in different leaves")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		btree.Put(991, 991)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("insert into another leaf waited for the held one")
+	}
+	path.release()
+	btree.mu.RUnlock()
+
+	btree.Put(1, 1)
+	if err := btree.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
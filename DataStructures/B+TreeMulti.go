@@ -0,0 +1,178 @@
+package DataStructures
+
+import "cmp"
+
+// MultiBTree is a B+ Tree that allows duplicate keys, for secondary indexes on
+// columns like status or last_name. Every entry is a (key, row id) pair stored
+// as its own key in the tree, ordered by key and then by row id, so a key with
+// many rows costs no more to change than one with a single row.
+// Like BTree it is safe for concurrent use
+type MultiBTree[K Ordered, V Ordered] struct {
+	tree *BTree[multiKey[K, V], struct{}]
+}
+
+// multiKey is one entry of a MultiBTree. Entries have 'bound' 0, a bound of
+// -1 or 1 sorts before or after every entry of 'key' and is only searched for
+type multiKey[K Ordered, V Ordered] struct {
+	key   K
+	rowID V
+	bound int8
+}
+
+// compareMultiKey orders entries by key, then row id
+func compareMultiKey[K Ordered, V Ordered](a, b multiKey[K, V]) int {
+	if c := cmp.Compare(a.key, b.key); c != 0 {
+		return c
+	}
+	if a.bound != b.bound {
+		return cmp.Compare(a.bound, b.bound)
+	}
+	return cmp.Compare(a.rowID, b.rowID)
+}
+
+// lowest and highest bound every entry of 'key'
+func lowest[K Ordered, V Ordered](key K) multiKey[K, V]  { return multiKey[K, V]{key: key, bound: -1} }
+func highest[K Ordered, V Ordered](key K) multiKey[K, V] { return multiKey[K, V]{key: key, bound: 1} }
+
+// newMultiBTree creates a non-unique B+ Tree of the given degree
+func newMultiBTree[K Ordered, V Ordered](degree uint16) *MultiBTree[K, V] {
+	return &MultiBTree[K, V]{tree: newBTreeFunc[multiKey[K, V], struct{}](degree, compareMultiKey[K, V])}
+}
+
+// newCountedMultiBTree is newMultiBTree with O(log n) Rank and CountRange,
+// at the cost described in newCountedBTree
+func newCountedMultiBTree[K Ordered, V Ordered](degree uint16) *MultiBTree[K, V] {
+	return &MultiBTree[K, V]{tree: newCountedBTreeFunc[multiKey[K, V], struct{}](degree, compareMultiKey[K, V])}
+}
+
+// Insert adds the entry (key, value). A row is indexed under a key once,
+// inserting a pair that is already there changes nothing
+func (m *MultiBTree[K, V]) Insert(key K, value V) {
+	m.tree.Put(multiKey[K, V]{key: key, rowID: value}, struct{}{})
+}
+
+// Delete removes the exact (key, value) entry
+func (m *MultiBTree[K, V]) Delete(key K, value V) bool {
+	_, found := m.tree.Delete(multiKey[K, V]{key: key, rowID: value})
+	return found
+}
+
+// DeleteAll removes every entry under 'key' and returns how many there were
+func (m *MultiBTree[K, V]) DeleteAll(key K) int {
+	return m.tree.DeleteRange(lowest[K, V](key), highest[K, V](key))
+}
+
+// Get returns the values stored under 'key' in row id order
+func (m *MultiBTree[K, V]) Get(key K) []V {
+	var values []V
+	for it := m.Range(key, key, true, true); it.Valid(); it.Next() {
+		values = append(values, it.Value())
+	}
+	return values
+}
+
+// Count returns how many entries 'key' has
+func (m *MultiBTree[K, V]) Count(key K) int {
+	return m.CountRange(key, key, true, true)
+}
+
+// Search checks if 'key' has at least one entry
+func (m *MultiBTree[K, V]) Search(key K) bool {
+	it := m.Seek(key)
+	return it.Valid() && it.Key() == key
+}
+
+// Min returns the smallest key in the tree
+func (m *MultiBTree[K, V]) Min() (K, bool) {
+	entry, ok := m.tree.Min()
+	return entry.key, ok
+}
+
+// Max returns the largest key in the tree
+func (m *MultiBTree[K, V]) Max() (K, bool) {
+	entry, ok := m.tree.Max()
+	return entry.key, ok
+}
+
+// Rank returns how many entries have a key smaller than 'key', see BTree.Rank
+func (m *MultiBTree[K, V]) Rank(key K) int {
+	return m.tree.Rank(lowest[K, V](key))
+}
+
+// CountRange returns how many entries have a key between 'lo' and 'hi', each
+// end included or not like Range, see BTree.CountRange
+func (m *MultiBTree[K, V]) CountRange(lo, hi K, loInclusive, hiInclusive bool) int {
+	from, to := multiBounds[K, V](lo, hi, loInclusive, hiInclusive)
+	return m.tree.CountRange(from, to, true, true)
+}
+
+// Stats reports the shape of the underlying tree, Keys counts entries
+func (m *MultiBTree[K, V]) Stats() TreeStats {
+	return m.tree.Stats()
+}
+
+// multiBounds turns a key range into the entries just outside or inside its ends
+func multiBounds[K Ordered, V Ordered](lo, hi K, loInclusive, hiInclusive bool) (multiKey[K, V], multiKey[K, V]) {
+	from, to := highest[K, V](lo), lowest[K, V](hi)
+	if loInclusive {
+		from = lowest[K, V](lo)
+	}
+	if hiInclusive {
+		to = highest[K, V](hi)
+	}
+	return from, to
+}
+
+// Seek returns a cursor on the first entry with a key >= 'key'
+func (m *MultiBTree[K, V]) Seek(key K) Iterator[K, V] {
+	return &multiCursor[K, V]{m.tree.Seek(lowest[K, V](key))}
+}
+
+// SeekLast returns a cursor on the last entry with a key <= 'key'
+func (m *MultiBTree[K, V]) SeekLast(key K) Iterator[K, V] {
+	return &multiCursor[K, V]{m.tree.SeekLast(highest[K, V](key))}
+}
+
+// First returns a cursor on the first entry of the smallest key
+func (m *MultiBTree[K, V]) First() Iterator[K, V] {
+	return &multiCursor[K, V]{m.tree.First()}
+}
+
+// Last returns a cursor on the last entry of the largest key
+func (m *MultiBTree[K, V]) Last() Iterator[K, V] {
+	return &multiCursor[K, V]{m.tree.Last()}
+}
+
+// Range returns a cursor on the first entry between 'lo' and 'hi',
+// duplicates come back in row id order
+func (m *MultiBTree[K, V]) Range(lo, hi K, loInclusive, hiInclusive bool) Iterator[K, V] {
+	from, to := multiBounds[K, V](lo, hi, loInclusive, hiInclusive)
+	return &multiCursor[K, V]{m.tree.Range(from, to, true, true)}
+}
+
+// ReverseRange is Range positioned on the last entry between 'lo' and 'hi'
+func (m *MultiBTree[K, V]) ReverseRange(lo, hi K, loInclusive, hiInclusive bool) Iterator[K, V] {
+	from, to := multiBounds[K, V](lo, hi, loInclusive, hiInclusive)
+	return &multiCursor[K, V]{m.tree.ReverseRange(from, to, true, true)}
+}
+
+// Validate checks the structure of the underlying tree
+func (m *MultiBTree[K, V]) Validate() error {
+	return m.tree.Validate()
+}
+
+// Display prints the keys of the tree level by level
+func (m *MultiBTree[K, V]) Display() {
+	m.tree.Display()
+}
+
+// multiCursor shows the entries of the underlying tree as (key, row id) pairs
+type multiCursor[K Ordered, V Ordered] struct {
+	entries Iterator[multiKey[K, V], struct{}]
+}
+
+func (c *multiCursor[K, V]) Valid() bool { return c.entries.Valid() }
+func (c *multiCursor[K, V]) Next()       { c.entries.Next() }
+func (c *multiCursor[K, V]) Prev()       { c.entries.Prev() }
+func (c *multiCursor[K, V]) Key() K      { return c.entries.Key().key }
+func (c *multiCursor[K, V]) Value() V    { return c.entries.Key().rowID }
@@ -0,0 +1,227 @@
+package DataStructures
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+type multiEntry struct {
+	key   string
+	rowID int
+}
+
+func collectEntries(it Iterator[string, int], reverse bool) []multiEntry {
+	var out []multiEntry
+	for it.Valid() {
+		out = append(out, multiEntry{it.Key(), it.Value()})
+		if reverse {
+			it.Prev()
+		} else {
+			it.Next()
+		}
+	}
+	return out
+}
+
+func TestMultiBTreeDuplicates(t *testing.T) {
+	index := newMultiBTree[string, int](4)
+	statuses := []string{"active", "banned", "pending"}
+	for row := 0; row < 300; row++ {
+		index.Insert(statuses[row%3], row)
+	}
+
+	for s, status := range statuses {
+		rows := index.Get(status)
+		if len(rows) != 100 || index.Count(status) != 100 {
+			t.Fatalf("%s: expected 100 rows, got %d", status, len(rows))
+		}
+		for i, row := range rows {
+			if row != i*3+s {
+				t.Fatalf("%s: rows not in insertion order at %d: %v", status, i, rows[:i+1])
+			}
+		}
+	}
+	if index.Search("deleted") || index.Count("deleted") != 0 {
+		t.Errorf("Unknown key reported as present")
+	}
+
+	// A row is indexed under a key once
+	index.Insert("active", 0)
+	if index.Count("active") != 100 {
+		t.Errorf("Inserting an existing pair again should change nothing")
+	}
+	if err := index.Validate(); err != nil {
+		t.Fatalf("%v", err)
+	}
+}
+
+func TestMultiBTreeDeleteEntry(t *testing.T) {
+	index := newMultiBTree[string, int](3)
+	for _, name := range []string{"smith", "jones", "smith", "brown", "smith"} {
+		index.Insert(name, len(index.Get(name))+10*len(name))
+	}
+	// smith -> [50 51 52], jones -> [50], brown -> [50]
+
+	if !index.Delete("smith", 51) {
+		t.Fatalf("Delete(smith, 51) should remove an existing entry")
+	}
+	if index.Delete("smith", 51) {
+		t.Errorf("Delete(smith, 51) twice should not find the entry again")
+	}
+	if index.Delete("nobody", 50) {
+		t.Errorf("Delete on a missing key should report false")
+	}
+	if got := index.Get("smith"); !equalKeys(got, []int{50, 52}) {
+		t.Errorf("Expected smith -> [50 52], got %v", got)
+	}
+
+	// Removing the last entry of a key removes the key
+	index.Delete("jones", 50)
+	if index.Search("jones") {
+		t.Errorf("jones should be gone after its only entry was deleted")
+	}
+
+	// Only the exact pair goes
+	index.Insert("brown", 51)
+	index.Delete("brown", 50)
+	if got := index.Get("brown"); !equalKeys(got, []int{51}) {
+		t.Errorf("Expected brown -> [51], got %v", got)
+	}
+
+	if n := index.DeleteAll("smith"); n != 2 || index.Search("smith") {
+		t.Errorf("DeleteAll(smith) removed %d entries", n)
+	}
+	if err := index.Validate(); err != nil {
+		t.Fatalf("%v", err)
+	}
+}
+
+func TestMultiBTreeRange(t *testing.T) {
+	index := newMultiBTree[string, int](4)
+	var expected []multiEntry
+	names := []string{"adams", "baker", "clark", "davis", "evans", "fox", "green"}
+	for row := 0; row < 70; row++ {
+		index.Insert(names[row%len(names)], row)
+	}
+	// Expected order: by key, then row id within a key
+	for _, name := range names {
+		for row := 0; row < 70; row++ {
+			if names[row%len(names)] == name && name >= "baker" && name < "fox" {
+				expected = append(expected, multiEntry{name, row})
+			}
+		}
+	}
+
+	got := collectEntries(index.Range("baker", "fox", true, false), false)
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Fatalf("Range(baker, fox) got %v\nexpected %v", got, expected)
+	}
+
+	reversed := collectEntries(index.ReverseRange("baker", "fox", true, false), true)
+	for i := range reversed {
+		if reversed[i] != expected[len(expected)-1-i] {
+			t.Fatalf("ReverseRange is not the mirror of Range at %d", i)
+		}
+	}
+
+	if first := index.First(); !first.Valid() || first.Key() != "adams" || first.Value() != 0 {
+		t.Errorf("First expected (adams, 0)")
+	}
+	if last := index.Last(); !last.Valid() || last.Key() != "green" || last.Value() != 69 {
+		t.Errorf("Last expected (green, 69)")
+	}
+	if c := index.Seek("b"); !c.Valid() || c.Key() != "baker" || c.Value() != 1 {
+		t.Errorf("Seek(b) expected (baker, 1)")
+	}
+	if c := index.SeekLast("b"); !c.Valid() || c.Key() != "adams" || c.Value() != 63 {
+		t.Errorf("SeekLast(b) expected (adams, 63)")
+	}
+}
+
+func TestMultiBTreeConcurrentInserts(t *testing.T) {
+	index := newMultiBTree[int, int](4)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(w)))
+			for i := 0; i < 500; i++ {
+				// Few distinct keys so writers keep colliding on the same posting lists
+				index.Insert(rng.Intn(20), w*1000+i)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	total := 0
+	for k := 0; k < 20; k++ {
+		total += index.Count(k)
+	}
+	if total != 4000 {
+		t.Fatalf("Expected 4000 entries, got %d", total)
+	}
+	if got := len(collectEntriesInt(index.First())); got != 4000 {
+		t.Fatalf("Full scan expected 4000 entries, got %d", got)
+	}
+}
+
+func TestMultiBTreeCountsEntries(t *testing.T) {
+	index := newCountedMultiBTree[int, int](4)
+	// Key k has k+1 rows
+	for k := 0; k < 10; k++ {
+		for row := 0; row <= k; row++ {
+			index.Insert(k, 100*k+row)
+		}
+	}
+	if got := index.Rank(5); got != 15 {
+		t.Errorf("Rank(5) = %d, want 15 entries below it", got)
+	}
+	if got := index.CountRange(2, 4, true, true); got != 12 {
+		t.Errorf("CountRange[2, 4] = %d, want 12", got)
+	}
+	if got := index.CountRange(2, 4, false, false); got != 4 {
+		t.Errorf("CountRange(2, 4) = %d, want 4", got)
+	}
+	if got := index.Stats().Keys; got != 55 {
+		t.Errorf("Stats().Keys = %d, want 55 entries", got)
+	}
+
+	if n := index.DeleteAll(9); n != 10 || index.CountRange(0, 9, true, true) != 45 {
+		t.Errorf("DeleteAll(9) removed %d entries", n)
+	}
+	if err := index.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestMultiBTreeHotKey keeps every row under one key, which must not make
+// inserts and deletes slower as the key grows
+func TestMultiBTreeHotKey(t *testing.T) {
+	index := newMultiBTree[string, int](16)
+	const rows = 100000
+	for row := 0; row < rows; row++ {
+		index.Insert("active", row)
+	}
+	for row := 0; row < rows; row += 2 {
+		if !index.Delete("active", row) {
+			t.Fatalf("Delete(active, %d) did not find the entry", row)
+		}
+	}
+	if got := index.Count("active"); got != rows/2 {
+		t.Fatalf("Count(active) = %d, want %d", got, rows/2)
+	}
+	if err := index.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func collectEntriesInt(it Iterator[int, int]) []int {
+	var out []int
+	for ; it.Valid(); it.Next() {
+		out = append(out, it.Value())
+	}
+	return out
+}
@@ -0,0 +1,156 @@
+package DataStructures
+
+// rangeCut collects what DeleteRange touched on its way down
+type rangeCut[K any, V any] struct {
+	lo, hi   K
+	leaves   []*bNode[K, V] // boundary leaves that were kept, in key order
+	internal []*bNode[K, V] // boundary internal nodes, children before parents
+}
+
+// DeleteRange removes every key between 'lo' and 'hi', both included, and
+// returns how many were removed. Subtrees that lie wholly inside the range are
+// dropped without being visited, only the two boundary paths are cut, and the
+// tree is rebalanced once along them at the end.
+// Like BulkLoad it needs the tree to itself and waits for other operations
+func (b *BTree[K, V]) DeleteRange(lo, hi K) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.root == nil || b.compare(lo, hi) > 0 {
+		return 0
+	}
+
+	// 1. Cut both boundary paths and drop everything between them
+	cut := &rangeCut[K, V]{lo: lo, hi: hi}
+	b.root = b.ownAlone(b.root, nil)
+	removed := b.cutRange(b.root, cut)
+	if removed == 0 {
+		return 0
+	}
+
+	// 2. The kept leaves are neighbours now, whatever lay between them is gone
+	for i := 1; i < len(cut.leaves); i++ {
+		left, right := cut.leaves[i-1], cut.leaves[i]
+		left.next = right
+		right.prev = left
+		right.version++
+	}
+
+	// 3. Rebalance bottom-up, a boundary node can be far below minKeys or even empty
+	for _, node := range cut.internal {
+		b.repair(node)
+	}
+	for !b.root.leaf && len(b.root.keys) == 0 {
+		b.root = b.root.children[0]
+		b.root.parent = nil
+	}
+	return removed
+}
+
+// cutRange removes the keys in [cut.lo, cut.hi] below 'node', which belongs to
+// the live tree, and returns how many it removed
+func (b *BTree[K, V]) cutRange(node *bNode[K, V], cut *rangeCut[K, V]) int {
+	if node.leaf {
+		_, start := b.leafIndex(node, cut.lo)
+		found, end := b.leafIndex(node, cut.hi)
+		if found {
+			end++
+		}
+		cut.leaves = append(cut.leaves, node)
+		if start == end {
+			return 0
+		}
+		node.keys = append(node.keys[:start], node.keys[end:]...)
+		node.values = append(node.values[:start], node.values[end:]...)
+		node.version++
+		return end - start
+	}
+
+	// Every child strictly between the ones 'lo' and 'hi' route to only holds
+	// keys inside the range. Dropping them leaves keys[j-1] as the separator
+	// between the two that stay
+	i, j := b.childIndex(node, cut.lo), b.childIndex(node, cut.hi)
+	removed := 0
+	if j > i+1 {
+		for _, child := range node.children[i+1 : j] {
+			removed += child.size()
+		}
+		node.keys = append(node.keys[:i], node.keys[j-1:]...)
+		node.children = append(node.children[:i+1], node.children[j:]...)
+		if node.counts != nil {
+			node.counts = append(node.counts[:i+1], node.counts[j:]...)
+		}
+		j = i + 1
+	}
+
+	for k := i; k <= j; k++ {
+		child := b.ownAlone(node.children[k], node)
+		n := b.cutRange(child, cut)
+		if node.counts != nil {
+			node.counts[k] -= n
+		}
+		removed += n
+	}
+	cut.internal = append(cut.internal, node)
+	return removed
+}
+
+// repair fixes every underfilled child of 'node'. Neighbours share keys one at
+// a time like rebalance does, and are merged once neither can spare any.
+// A node left with a single child is fixed by repairing its own parent
+func (b *BTree[K, V]) repair(node *bNode[K, V]) {
+	for i := 0; i < len(node.children) && len(node.children) > 1; {
+		if !node.children[i].underFill(b.degree) {
+			i++
+			continue
+		}
+		left := i
+		if left == len(node.children)-1 {
+			left--
+		}
+		b.combine(node, left)
+		i = left
+	}
+}
+
+// combine evens out children[i] and children[i+1] of 'parent' so neither is
+// underfilled, merging them if there are not enough keys for two nodes
+func (b *BTree[K, V]) combine(parent *bNode[K, V], i int) {
+	left := b.ownAlone(parent.children[i], parent)
+	right := b.ownAlone(parent.children[i+1], parent)
+	for left.underFill(b.degree) && right.canLend(b.degree) {
+		borrowFromRight(left, right, parent, i)
+	}
+	for right.underFill(b.degree) && left.canLend(b.degree) {
+		borrowFromLeft(right, left, parent, i)
+	}
+
+	// Whatever moved may have brought underfilled grandchildren along
+	if left.underFill(b.degree) || right.underFill(b.degree) {
+		// One of them is short and the other has nothing to spare, so
+		// together they fit in one node
+		b.merges.Add(1)
+		joinSiblings(left, right, parent, i)
+		b.repair(left)
+		return
+	}
+	b.repair(left)
+	b.repair(right)
+}
+
+// ownAlone is own for a caller that has the whole tree to itself
+func (b *BTree[K, V]) ownAlone(node, parent *bNode[K, V]) *bNode[K, V] {
+	node.latch.Lock()
+	node = b.own(node, parent, nil)
+	node.latch.Unlock()
+	return node
+}
+
+// Truncate removes every key in O(1) by starting over with an empty root.
+// Snapshots keep the nodes they share, everything else is left to the garbage collector
+func (b *BTree[K, V]) Truncate() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.root = b.newNode(true)
+}
@@ -0,0 +1,126 @@
+package DataStructures
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestDeleteRange(t *testing.T) {
+	for _, degree := range []uint16{3, 4, 5, 8} {
+		r := rand.New(rand.NewSource(int64(degree)))
+		for round := 0; round < 60; round++ {
+			n := r.Intn(600)
+			btree := newBTree[int, int](degree)
+			present := map[int]bool{}
+			for _, k := range r.Perm(n) {
+				btree.Put(k*2, k)
+				present[k*2] = true
+			}
+			lo := r.Intn(2*n+20) - 10
+			hi := lo + r.Intn(2*n+20)
+			if round%10 == 0 {
+				// Everything, or a range off either end
+				lo, hi = -5, 2*n+5
+			}
+
+			want := 0
+			for k := range present {
+				if k >= lo && k <= hi {
+					want++
+					delete(present, k)
+				}
+			}
+			if got := btree.DeleteRange(lo, hi); got != want {
+				t.Fatalf("degree %d n %d: DeleteRange(%d, %d) = %d, want %d", degree, n, lo, hi, got, want)
+			}
+			if err := btree.Validate(); err != nil {
+				t.Fatalf("degree %d n %d: DeleteRange(%d, %d): %v", degree, n, lo, hi, err)
+			}
+			var keys []int
+			for k := range present {
+				keys = append(keys, k)
+			}
+			sort.Ints(keys)
+			if got := leafKeys(btree); !equalKeys(got, keys) {
+				t.Fatalf("degree %d n %d: DeleteRange(%d, %d) left %v, want %v", degree, n, lo, hi, got, keys)
+			}
+			if got := collectKeysReverse(btree.Last()); len(got) != len(keys) {
+				t.Fatalf("degree %d n %d: reverse scan found %d keys, want %d", degree, n, len(got), len(keys))
+			}
+
+			// The tree keeps working afterwards
+			btree.Put(lo, -1)
+			btree.Delete(hi + 2)
+			if err := btree.Validate(); err != nil {
+				t.Fatalf("degree %d n %d: after updates: %v", degree, n, err)
+			}
+		}
+	}
+}
+
+func TestDeleteRangeEdgeCases(t *testing.T) {
+	btree := newBTree[int, int](4)
+	if got := btree.DeleteRange(0, 10); got != 0 {
+		t.Errorf("empty tree: removed %d", got)
+	}
+	for k := 0; k < 50; k++ {
+		btree.Put(k, k)
+	}
+	if got := btree.DeleteRange(30, 20); got != 0 {
+		t.Errorf("lo > hi removed %d", got)
+	}
+	if got := btree.DeleteRange(25, 25); got != 1 || btree.Search(25) {
+		t.Errorf("single key range removed %d", got)
+	}
+	if got := btree.DeleteRange(25, 25); got != 0 {
+		t.Errorf("repeated range removed %d", got)
+	}
+	if err := btree.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeleteRangeKeepsSnapshots(t *testing.T) {
+	btree := newCountedBTree[int, int](4)
+	want := map[int]int{}
+	for k := 0; k < 300; k++ {
+		btree.Put(k, k)
+		want[k] = k
+	}
+	snap := btree.Snapshot()
+	if got := btree.DeleteRange(50, 249); got != 200 {
+		t.Fatalf("DeleteRange removed %d", got)
+	}
+	if err := btree.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSnapshot(snap, want); err != nil {
+		t.Fatal(err)
+	}
+	if got := btree.CountRange(0, 299, true, true); got != 100 {
+		t.Fatalf("tree holds %d keys, want 100", got)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	btree := newCountedBTree[int, int](3)
+	for k := 0; k < 100; k++ {
+		btree.Put(k, k)
+	}
+	snap := btree.Snapshot()
+	btree.Truncate()
+	if btree.Search(5) || btree.First().Valid() || btree.Rank(1000) != 0 {
+		t.Fatal("truncated tree still holds keys")
+	}
+	if err := btree.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if snap.Len() != 100 {
+		t.Fatalf("snapshot lost keys: %d left", snap.Len())
+	}
+	btree.Put(7, 7)
+	if v, ok := btree.Get(7); !ok || v != 7 {
+		t.Fatal("truncated tree does not accept inserts")
+	}
+}
@@ -0,0 +1,150 @@
+package DataStructures
+
+// Order statistics. Every internal node of a counted tree (see
+// newCountedBTree) keeps counts[i], the number of keys below children[i], so
+// positions can be found by adding counts on the way down instead of walking
+// the leaves. Other trees do not keep counts and walk the leaves instead, in O(n).
+//
+// Counts only change while a writer holds the root latch (see B+TreeLatch.go),
+// so holding it shared for the whole descent gives a consistent answer.
+// Writers that only replace values may still be running, which is why the
+// leaf is read latched before a value is read.
+
+// Rank returns how many keys in the tree are smaller than 'key'.
+// For a key in the tree that is its 0-based position in key order
+func (b *BTree[K, V]) Rank(key K) int {
+	if !b.counted {
+		return b.scanRank(key)
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	b.rootLatch.RLock()
+	defer b.rootLatch.RUnlock()
+
+	return b.countBelow(key, false)
+}
+
+// Select returns the entry at 0-based position 'i' in key order,
+// or false if 'i' is out of range
+func (b *BTree[K, V]) Select(i int) (K, V, bool) {
+	var zeroK K
+	var zeroV V
+	if !b.counted {
+		return b.scanSelect(i)
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	b.rootLatch.RLock()
+	defer b.rootLatch.RUnlock()
+
+	leaf, index := b.selectLeaf(i)
+	if leaf == nil {
+		return zeroK, zeroV, false
+	}
+	leaf.latch.RLock()
+	defer leaf.latch.RUnlock()
+	return leaf.keys[index], leaf.values[index], true
+}
+
+// selectLeaf finds the leaf holding the entry at position 'i' and its index there,
+// or nil if 'i' is out of range. The caller holds the root latch
+func (b *BTree[K, V]) selectLeaf(i int) (*bNode[K, V], int) {
+	node := b.root
+	if node == nil || i < 0 || i >= node.size() {
+		return nil, 0
+	}
+	for !node.leaf {
+		child := 0
+		for i >= node.counts[child] {
+			i -= node.counts[child]
+			child++
+		}
+		node = node.children[child]
+	}
+	return node, i
+}
+
+// CountRange returns how many keys lie between 'lo' and 'hi',
+// each end included or not like Range
+func (b *BTree[K, V]) CountRange(lo, hi K, loInclusive, hiInclusive bool) int {
+	if !b.counted {
+		return b.scanCountRange(lo, hi, loInclusive, hiInclusive)
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	b.rootLatch.RLock()
+	defer b.rootLatch.RUnlock()
+
+	return b.countRange(lo, hi, loInclusive, hiInclusive)
+}
+
+// countRange is CountRange for a caller that holds the root latch
+func (b *BTree[K, V]) countRange(lo, hi K, loInclusive, hiInclusive bool) int {
+	if b.compare(lo, hi) > 0 {
+		return 0
+	}
+	count := b.countBelow(hi, hiInclusive) - b.countBelow(lo, !loInclusive)
+	if count < 0 {
+		// lo == hi with only one end included
+		return 0
+	}
+	return count
+}
+
+// countBelow returns how many keys are smaller than 'key', or smaller or equal
+// if 'inclusive' is set. The caller holds the root latch
+func (b *BTree[K, V]) countBelow(key K, inclusive bool) int {
+	node := b.root
+	if node == nil {
+		return 0
+	}
+	below := 0
+	for !node.leaf {
+		// Every key under the children before 'i' is smaller than 'key'
+		i := b.childIndex(node, key)
+		for _, count := range node.counts[:i] {
+			below += count
+		}
+		node = node.children[i]
+	}
+	found, index := b.leafIndex(node, key)
+	if found && inclusive {
+		index++
+	}
+	return below + index
+}
+
+// scanRank is Rank for a tree without counts
+func (b *BTree[K, V]) scanRank(key K) int {
+	rank := 0
+	for it := b.First(); it.Valid() && b.compare(it.Key(), key) < 0; it.Next() {
+		rank++
+	}
+	return rank
+}
+
+// scanSelect is Select for a tree without counts
+func (b *BTree[K, V]) scanSelect(i int) (K, V, bool) {
+	var zeroK K
+	var zeroV V
+	if i < 0 {
+		return zeroK, zeroV, false
+	}
+	it := b.First()
+	for ; it.Valid() && i > 0; it.Next() {
+		i--
+	}
+	if !it.Valid() {
+		return zeroK, zeroV, false
+	}
+	return it.Key(), it.Value(), true
+}
+
+// scanCountRange is CountRange for a tree without counts
+func (b *BTree[K, V]) scanCountRange(lo, hi K, loInclusive, hiInclusive bool) int {
+	count := 0
+	for it := b.Range(lo, hi, loInclusive, hiInclusive); it.Valid(); it.Next() {
+		count++
+	}
+	return count
+}
@@ -0,0 +1,197 @@
+package DataStructures
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// checkOrderStatistics compares Rank, Select and CountRange with a sorted copy of the keys
+func checkOrderStatistics(t *testing.T, btree *BTree[int, int], keys []int) {
+	t.Helper()
+	sorted := append([]int{}, keys...)
+	sort.Ints(sorted)
+	for i, k := range sorted {
+		if got := btree.Rank(k); got != i {
+			t.Fatalf("Rank(%d) = %d, want %d", k, got, i)
+		}
+		if got := btree.Rank(k + 1); got != i+1 {
+			// Keys are even, so k+1 is never in the tree
+			t.Fatalf("Rank(%d) = %d, want %d", k+1, got, i+1)
+		}
+		if key, value, ok := btree.Select(i); !ok || key != k || value != k*10 {
+			t.Fatalf("Select(%d) = (%d, %d, %v), want (%d, %d, true)", i, key, value, ok, k, k*10)
+		}
+	}
+	if _, _, ok := btree.Select(len(sorted)); ok {
+		t.Fatalf("Select(%d) past the end found an entry", len(sorted))
+	}
+	if _, _, ok := btree.Select(-1); ok {
+		t.Fatal("Select(-1) found an entry")
+	}
+	if len(sorted) < 2 {
+		return
+	}
+	lo, hi := sorted[len(sorted)/4], sorted[3*len(sorted)/4]
+	want := 3*len(sorted)/4 - len(sorted)/4 + 1
+	if got := btree.CountRange(lo, hi, true, true); got != want {
+		t.Fatalf("CountRange[%d, %d] = %d, want %d", lo, hi, got, want)
+	}
+	if got := btree.CountRange(lo, hi, false, false); got != want-2 && !(lo == hi && got == 0) {
+		t.Fatalf("CountRange(%d, %d) = %d, want %d", lo, hi, got, want-2)
+	}
+}
+
+func TestOrderStatistics(t *testing.T) {
+	for _, degree := range []uint16{3, 4, 5, 8} {
+		btree := newCountedBTree[int, int](degree)
+		r := rand.New(rand.NewSource(int64(degree)))
+		present := map[int]bool{}
+		for op := 0; op < 2000; op++ {
+			k := r.Intn(500) * 2
+			if r.Intn(3) == 0 {
+				btree.Delete(k)
+				delete(present, k)
+			} else {
+				btree.Put(k, k*10)
+				present[k] = true
+			}
+		}
+		if err := btree.Validate(); err != nil {
+			t.Fatalf("degree %d: %v", degree, err)
+		}
+		var keys []int
+		for k := range present {
+			keys = append(keys, k)
+		}
+		checkOrderStatistics(t, btree, keys)
+
+		// Drain the tree so every merge and root shrink runs
+		for _, k := range keys {
+			btree.Delete(k)
+		}
+		if err := btree.Validate(); err != nil {
+			t.Fatalf("degree %d: after draining: %v", degree, err)
+		}
+		if got := btree.CountRange(0, 1000, true, true); got != 0 {
+			t.Fatalf("degree %d: drained tree counts %d keys", degree, got)
+		}
+	}
+}
+
+func TestCountRangeBounds(t *testing.T) {
+	for _, btree := range []*BTree[int, int]{newCountedBTree[int, int](4), newBTree[int, int](4)} {
+		for k := 0; k < 100; k += 2 {
+			btree.Put(k, k*10)
+		}
+		checkCountRangeBounds(t, btree)
+	}
+}
+
+func checkCountRangeBounds(t *testing.T, btree *BTree[int, int]) {
+	t.Helper()
+	cases := []struct {
+		lo, hi         int
+		loIncl, hiIncl bool
+		want           int
+	}{
+		{10, 20, true, true, 6},
+		{10, 20, false, true, 5},
+		{10, 20, true, false, 5},
+		{10, 20, false, false, 4},
+		{11, 19, true, true, 4},
+		{-50, 500, true, true, 50},
+		{20, 10, true, true, 0},
+		{10, 10, true, true, 1},
+		{10, 10, false, true, 0},
+		{11, 11, true, true, 0},
+	}
+	for _, c := range cases {
+		if got := btree.CountRange(c.lo, c.hi, c.loIncl, c.hiIncl); got != c.want {
+			t.Errorf("counted %v: CountRange(%d, %d, %v, %v) = %d, want %d", btree.counted, c.lo, c.hi, c.loIncl, c.hiIncl, got, c.want)
+		}
+	}
+}
+
+func TestOrderStatisticsWithoutCounts(t *testing.T) {
+	btree := newBTree[int, int](4)
+	var keys []int
+	for k := 0; k < 500; k += 2 {
+		btree.Put(k, k*10)
+		keys = append(keys, k)
+	}
+	checkOrderStatistics(t, btree, keys)
+
+	snap := btree.Snapshot()
+	for _, k := range keys {
+		btree.Delete(k)
+	}
+	if got := snap.Rank(100); got != 50 {
+		t.Fatalf("snapshot Rank(100) = %d, want 50", got)
+	}
+	if key, _, ok := snap.Select(50); !ok || key != 100 {
+		t.Fatalf("snapshot Select(50) = (%d, %v), want (100, true)", key, ok)
+	}
+	if got := snap.CountRange(100, 200, true, false); got != 50 {
+		t.Fatalf("snapshot CountRange[100, 200) = %d, want 50", got)
+	}
+}
+
+func TestOrderStatisticsAfterBulkLoad(t *testing.T) {
+	keys := make([]int, 777)
+	values := make([]int, len(keys))
+	for i := range keys {
+		keys[i] = i * 2
+		values[i] = keys[i] * 10
+	}
+	btree := newCountedBTree[int, int](5)
+	if err := btree.BulkLoad(mustSliceIterator(t, keys, values), 0.8); err != nil {
+		t.Fatal(err)
+	}
+	if err := btree.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	checkOrderStatistics(t, btree, keys)
+}
+
+func TestConcurrentRankDuringWrites(t *testing.T) {
+	btree := fillStable(newCountedBTree[int, int](4))
+	var wg sync.WaitGroup
+	expected := runWriters(btree, 4, 2000, &wg)
+
+	errs := make(chan string, 16)
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(200 + r)))
+			for i := 0; i < 2000; i++ {
+				i := rng.Intn(stableKeys)
+				k := i * stableStride
+				// Writers only add keys between stable ones, never below 0
+				if rank := btree.Rank(k); rank < i {
+					errs <- "Rank below the number of stable keys under it"
+					return
+				}
+				if n := btree.CountRange(k, k, true, true); n != 1 {
+					errs <- "CountRange missed a stable key"
+					return
+				}
+			}
+		}(r)
+	}
+	wg.Wait()
+	close(errs)
+	for msg := range errs {
+		t.Fatal(msg)
+	}
+
+	if err := btree.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	want := stableKeys + countEntries(expected)
+	if got := btree.CountRange(-1, stableKeys*stableStride, true, true); got != want {
+		t.Fatalf("CountRange over everything = %d, want %d", got, want)
+	}
+}
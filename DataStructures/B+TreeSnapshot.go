@@ -0,0 +1,250 @@
+package DataStructures
+
+import "slices"
+
+/*
+Copy-on-write snapshots.
+
+Snapshot hands out the current root and bumps the tree's generation. Every
+node created before that is now shared with the snapshot, and the live tree
+never changes the keys, values, children or counts of a shared node again.
+Instead a writer copies it first: a crabCount path is copied top-down from the
+root to the leaf, and rebalancing also copies the siblings it is about to
+change. Nodes created after the snapshot belong to the live tree alone and are
+changed in place as before, so a tree nobody has snapshotted never copies.
+
+The parent, next and prev links of shared nodes do keep changing, they have to
+point at the live copies. A snapshot therefore only ever walks down from its
+root: its cursors find neighbouring leaves through the internal nodes. The
+old node a copy replaced is marked stale so live cursors that reach it through
+a link they read earlier know to find their place again from the root.
+*/
+
+// Snapshot is an immutable view of a BTree at the moment Snapshot was called.
+// It is read without any latches and never blocks, or is blocked by, writers
+// on the tree it came from. It shares every node the tree has not changed since
+type Snapshot[K any, V any] struct {
+	view *BTree[K, V]
+}
+
+// Snapshot returns a read-only view of the tree as it is now. It is cheap:
+// nothing is copied until the tree is next written
+func (b *BTree[K, V]) Snapshot() *Snapshot[K, V] {
+	// Waiting out every point operation means no value write is half done
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.gen++
+	return &Snapshot[K, V]{view: &BTree[K, V]{
+		degree:  b.degree,
+		root:    b.root,
+		compare: b.compare,
+		counted: b.counted,
+		frozen:  true,
+	}}
+}
+
+// own makes every node on a crabCount path private to the live tree, top-down
+// so each copy can be hung off an already private parent
+func (p *latchPath[K, V]) own() {
+	for i, node := range p.nodes {
+		var parent *bNode[K, V]
+		if i > 0 {
+			parent = p.nodes[i-1]
+		}
+		p.nodes[i] = p.tree.own(node, parent, nil)
+	}
+}
+
+// own returns 'node' if it belongs to the live tree, or else a private copy that
+// takes its place under 'parent' (nil for the root). The caller holds 'node' and
+// 'parent' write latched, the copy comes back write latched and 'node' is let go.
+// 'held' is a latched leaf next to 'node' that must not be latched again
+func (b *BTree[K, V]) own(node, parent, held *bNode[K, V]) *bNode[K, V] {
+	if node.gen == b.gen {
+		return node
+	}
+
+	clone := &bNode[K, V]{
+		keys:     append([]K{}, node.keys...),
+		values:   append([]V{}, node.values...),
+		children: append([]*bNode[K, V]{}, node.children...),
+		counts:   slices.Clone(node.counts),
+		parent:   parent,
+		leaf:     node.leaf,
+		next:     node.next,
+		prev:     node.prev,
+		gen:      b.gen,
+	}
+	clone.latch.Lock()
+
+	if parent == nil {
+		b.root = clone
+	} else {
+		parent.children[findChildIndex(parent, node)] = clone
+	}
+	for _, child := range clone.children {
+		child.parent = clone
+	}
+	if clone.leaf {
+		// Point the neighbours at the copy. A new prev link counts as a change
+		// for cursors, like in Split
+		relink := func(neighbour *bNode[K, V], fix func(*bNode[K, V])) {
+			if neighbour == nil {
+				return
+			}
+			if neighbour != held {
+				neighbour.latch.Lock()
+				defer neighbour.latch.Unlock()
+			}
+			fix(neighbour)
+		}
+		relink(clone.prev, func(n *bNode[K, V]) { n.next = clone })
+		relink(clone.next, func(n *bNode[K, V]) {
+			n.prev = clone
+			n.version++
+		})
+	}
+
+	node.stale = true
+	node.version++
+	node.latch.Unlock()
+	return clone
+}
+
+// frozenLeaf descends to the leaf 'pick' leads to without latches, for a Snapshot view
+func (b *BTree[K, V]) frozenLeaf(pick func(*bNode[K, V]) int) *bNode[K, V] {
+	node := b.root
+	for node != nil && !node.leaf {
+		node = node.children[pick(node)]
+	}
+	return node
+}
+
+// leafAfter finds the leaf that follows 'leaf' by walking down from the root,
+// remembering the last point where the path could have gone one child right
+func (b *BTree[K, V]) leafAfter(leaf *bNode[K, V]) *bNode[K, V] {
+	if len(leaf.keys) == 0 {
+		// Only an empty root leaf has no keys
+		return nil
+	}
+	key := leaf.keys[len(leaf.keys)-1]
+	var after *bNode[K, V]
+	for node := b.root; !node.leaf; {
+		i := b.childIndex(node, key)
+		if i+1 < len(node.children) {
+			after = node.children[i+1]
+		}
+		node = node.children[i]
+	}
+	for after != nil && !after.leaf {
+		after = after.children[0]
+	}
+	return after
+}
+
+// leafBefore is leafAfter in the other direction
+func (b *BTree[K, V]) leafBefore(leaf *bNode[K, V]) *bNode[K, V] {
+	if len(leaf.keys) == 0 {
+		return nil
+	}
+	key := leaf.keys[0]
+	var before *bNode[K, V]
+	for node := b.root; !node.leaf; {
+		i := b.childIndex(node, key)
+		if i > 0 {
+			before = node.children[i-1]
+		}
+		node = node.children[i]
+	}
+	for before != nil && !before.leaf {
+		before = before.children[len(before.children)-1]
+	}
+	return before
+}
+
+// Get returns the value stored under 'key' when the snapshot was taken
+func (s *Snapshot[K, V]) Get(key K) (V, bool) {
+	var zero V
+	leaf := s.view.frozenLeaf(func(n *bNode[K, V]) int { return s.view.childIndex(n, key) })
+	if leaf == nil {
+		return zero, false
+	}
+	found, index := s.view.leafIndex(leaf, key)
+	if !found {
+		return zero, false
+	}
+	return leaf.values[index], true
+}
+
+// Search checks if 'key' was in the tree when the snapshot was taken
+func (s *Snapshot[K, V]) Search(key K) bool {
+	_, found := s.Get(key)
+	return found
+}
+
+// Len returns the number of keys in the snapshot
+func (s *Snapshot[K, V]) Len() int {
+	if s.view.root == nil {
+		return 0
+	}
+	return s.view.root.size()
+}
+
+// Seek returns a cursor on the first entry with a key >= 'key'
+func (s *Snapshot[K, V]) Seek(key K) Iterator[K, V] { return s.view.Seek(key) }
+
+// SeekLast returns a cursor on the last entry with a key <= 'key'
+func (s *Snapshot[K, V]) SeekLast(key K) Iterator[K, V] { return s.view.SeekLast(key) }
+
+// First returns a cursor on the smallest entry
+func (s *Snapshot[K, V]) First() Iterator[K, V] { return s.view.First() }
+
+// Last returns a cursor on the largest entry
+func (s *Snapshot[K, V]) Last() Iterator[K, V] { return s.view.Last() }
+
+// Range returns a cursor on the first key between 'lo' and 'hi', see BTree.Range
+func (s *Snapshot[K, V]) Range(lo, hi K, loInclusive, hiInclusive bool) Iterator[K, V] {
+	return s.view.Range(lo, hi, loInclusive, hiInclusive)
+}
+
+// ReverseRange is Range positioned on the last key between 'lo' and 'hi'
+func (s *Snapshot[K, V]) ReverseRange(lo, hi K, loInclusive, hiInclusive bool) Iterator[K, V] {
+	return s.view.ReverseRange(lo, hi, loInclusive, hiInclusive)
+}
+
+// Min returns the smallest key in the snapshot
+func (s *Snapshot[K, V]) Min() (K, bool) { return s.view.Min() }
+
+// Max returns the largest key in the snapshot
+func (s *Snapshot[K, V]) Max() (K, bool) { return s.view.Max() }
+
+// Rank returns how many keys in the snapshot are smaller than 'key'
+func (s *Snapshot[K, V]) Rank(key K) int {
+	if !s.view.counted {
+		return s.view.scanRank(key)
+	}
+	return s.view.countBelow(key, false)
+}
+
+// Select returns the entry at 0-based position 'i' in key order
+func (s *Snapshot[K, V]) Select(i int) (K, V, bool) {
+	var zeroK K
+	var zeroV V
+	if !s.view.counted {
+		return s.view.scanSelect(i)
+	}
+	leaf, index := s.view.selectLeaf(i)
+	if leaf == nil {
+		return zeroK, zeroV, false
+	}
+	return leaf.keys[index], leaf.values[index], true
+}
+
+// CountRange returns how many keys lie between 'lo' and 'hi', see BTree.CountRange
+func (s *Snapshot[K, V]) CountRange(lo, hi K, loInclusive, hiInclusive bool) int {
+	if !s.view.counted {
+		return s.view.scanCountRange(lo, hi, loInclusive, hiInclusive)
+	}
+	return s.view.countRange(lo, hi, loInclusive, hiInclusive)
+}
@@ -0,0 +1,256 @@
+package DataStructures
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// checkSnapshot compares every read path of 'snap' with 'want'
+func checkSnapshot(snap *Snapshot[int, int], want map[int]int) error {
+	keys := make([]int, 0, len(want))
+	for k := range want {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+
+	if snap.Len() != len(keys) {
+		return fmt.Errorf("Len = %d, want %d", snap.Len(), len(keys))
+	}
+	i := 0
+	for c := snap.First(); c.Valid(); c.Next() {
+		if i >= len(keys) || c.Key() != keys[i] || c.Value() != want[keys[i]] {
+			return fmt.Errorf("forward scan at %d: (%d, %d)", i, c.Key(), c.Value())
+		}
+		i++
+	}
+	if i != len(keys) {
+		return fmt.Errorf("forward scan returned %d of %d keys", i, len(keys))
+	}
+	for c := snap.Last(); c.Valid(); c.Prev() {
+		i--
+		if i < 0 || c.Key() != keys[i] {
+			return fmt.Errorf("reverse scan at %d: %d", i, c.Key())
+		}
+	}
+	if i != 0 {
+		return fmt.Errorf("reverse scan stopped %d keys short", i)
+	}
+	for i, k := range keys {
+		if v, ok := snap.Get(k); !ok || v != want[k] {
+			return fmt.Errorf("Get(%d) = (%d, %v), want %d", k, v, ok, want[k])
+		}
+		if !snap.view.counted {
+			// Without counts these are leaf scans, see TestOrderStatisticsWithoutCounts
+			continue
+		}
+		if r := snap.Rank(k); r != i {
+			return fmt.Errorf("Rank(%d) = %d, want %d", k, r, i)
+		}
+		if sk, sv, ok := snap.Select(i); !ok || sk != k || sv != want[k] {
+			return fmt.Errorf("Select(%d) = (%d, %d, %v)", i, sk, sv, ok)
+		}
+	}
+	return nil
+}
+
+func TestSnapshotIsolation(t *testing.T) {
+	for _, degree := range []uint16{3, 4, 7} {
+		testSnapshotIsolation(t, degree, newBTree[int, int](degree))
+		testSnapshotIsolation(t, degree, newCountedBTree[int, int](degree))
+	}
+}
+
+func testSnapshotIsolation(t *testing.T, degree uint16, btree *BTree[int, int]) {
+	live := map[int]int{}
+	r := rand.New(rand.NewSource(int64(degree)))
+
+	type taken struct {
+		snap *Snapshot[int, int]
+		want map[int]int
+	}
+	var snaps []taken
+	for round := 0; round < 8; round++ {
+		copied := make(map[int]int, len(live))
+		for k, v := range live {
+			copied[k] = v
+		}
+		snaps = append(snaps, taken{btree.Snapshot(), copied})
+
+		for op := 0; op < 300; op++ {
+			k := r.Intn(400)
+			switch r.Intn(4) {
+			case 0:
+				btree.Delete(k)
+				delete(live, k)
+			case 1:
+				// Value only writes must not leak into snapshots either
+				if _, ok := live[k]; ok {
+					btree.Put(k, -op)
+					live[k] = -op
+				}
+			default:
+				btree.Put(k, op)
+				live[k] = op
+			}
+		}
+		if err := btree.Validate(); err != nil {
+			t.Fatalf("degree %d round %d: %v", degree, round, err)
+		}
+	}
+
+	for i, s := range snaps {
+		if err := checkSnapshot(s.snap, s.want); err != nil {
+			t.Fatalf("degree %d snapshot %d: %v", degree, i, err)
+		}
+	}
+	if err := checkSnapshot(btree.Snapshot(), live); err != nil {
+		t.Fatalf("degree %d live: %v", degree, err)
+	}
+}
+
+// reachable collects every node below 'root'
+func reachable[K any, V any](root *bNode[K, V], into map[*bNode[K, V]]bool) {
+	if root == nil {
+		return
+	}
+	into[root] = true
+	for _, child := range root.children {
+		reachable(child, into)
+	}
+}
+
+func TestSnapshotCopiesOnlyThePath(t *testing.T) {
+	btree := newBTree[int, int](4)
+	for i := 0; i < 1000; i += 2 {
+		btree.Put(i, i)
+	}
+	snap := btree.Snapshot()
+	height := 0
+	for node := btree.root; node != nil; node = node.children[0] {
+		height++
+		if node.leaf {
+			break
+		}
+	}
+
+	// An update that does not split copies exactly one node per level
+	btree.Put(500, -1)
+	shared := map[*bNode[int, int]]bool{}
+	reachable(snap.view.root, shared)
+	current := map[*bNode[int, int]]bool{}
+	reachable(btree.root, current)
+	copied := 0
+	for node := range current {
+		if !shared[node] {
+			copied++
+		}
+	}
+	if copied != height {
+		t.Fatalf("a value update copied %d nodes, want %d (the tree height)", copied, height)
+	}
+	if v, _ := snap.Get(500); v != 500 {
+		t.Fatalf("snapshot sees the update: %d", v)
+	}
+
+	// A second write to the same leaf copies nothing more
+	btree.Put(502, -1)
+	current = map[*bNode[int, int]]bool{}
+	reachable(btree.root, current)
+	again := 0
+	for node := range current {
+		if !shared[node] {
+			again++
+		}
+	}
+	if again != copied {
+		t.Fatalf("writing a private leaf copied %d more nodes", again-copied)
+	}
+	if err := btree.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSnapshotOfEmptyTree(t *testing.T) {
+	btree := newBTree[int, int](3)
+	snap := btree.Snapshot()
+	btree.Put(1, 1)
+	if snap.Len() != 0 || snap.First().Valid() || snap.Search(1) {
+		t.Fatal("empty snapshot sees a later insert")
+	}
+	if _, ok := snap.Min(); ok {
+		t.Fatal("empty snapshot has a Min")
+	}
+}
+
+// TestSnapshotDuringWrites reads snapshots and live cursors while writers
+// churn and new snapshots keep being taken
+func TestSnapshotDuringWrites(t *testing.T) {
+	testSnapshotDuringWrites(t, stableTree(4))
+	testSnapshotDuringWrites(t, fillStable(newCountedBTree[int, int](4)))
+}
+
+func testSnapshotDuringWrites(t *testing.T, btree *BTree[int, int]) {
+	var wg sync.WaitGroup
+	runWriters(btree, 4, 3000, &wg)
+
+	errs := make(chan error, 16)
+	for r := 0; r < 3; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 30; i++ {
+				snap := btree.Snapshot()
+				want := map[int]int{}
+				for c := snap.First(); c.Valid(); c.Next() {
+					want[c.Key()] = c.Value()
+				}
+				for k := 0; k < stableKeys; k++ {
+					if _, ok := want[k*stableStride]; !ok {
+						errs <- fmt.Errorf("snapshot is missing stable key %d", k*stableStride)
+						return
+					}
+				}
+				// Writers keep going, the snapshot must not move
+				if err := checkSnapshot(snap, want); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	for r := 0; r < 2; r++ {
+		wg.Add(1)
+		go func(reverse bool) {
+			defer wg.Done()
+			for i := 0; i < 40; i++ {
+				stable := 0
+				next := btree.First()
+				move := next.Next
+				if reverse {
+					next = btree.Last()
+					move = next.Prev
+				}
+				for ; next.Valid(); move() {
+					if next.Key()%stableStride == 0 {
+						stable++
+					}
+				}
+				if stable != stableKeys {
+					errs <- fmt.Errorf("live scan saw %d stable keys, want %d", stable, stableKeys)
+					return
+				}
+			}
+		}(r == 1)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+	if err := btree.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,53 @@
+package DataStructures
+
+// TreeStats describes the shape of a BTree at one moment
+type TreeStats struct {
+	Height        int   // levels from the root down to the leaves, 0 for a tree without a root
+	NodesPerLevel []int // number of nodes on each level, the root level first
+	Keys          int   // keys stored in the leaves
+	Leaves        int
+
+	// Leaf fill is keys over the degree-1 a leaf can hold
+	AvgLeafFill float64
+	MinLeafFill float64
+
+	// Structural changes since the tree was created
+	Splits uint64
+	Merges uint64
+}
+
+// Stats walks every node of the tree and reports its shape.
+// Like Display it needs the tree to itself and waits for other operations
+func (b *BTree[K, V]) Stats() TreeStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := TreeStats{Splits: b.splits.Load(), Merges: b.merges.Load()}
+	if b.root == nil {
+		return stats
+	}
+
+	capacity := float64(b.degree - 1)
+	stats.MinLeafFill = 1
+	level := []*bNode[K, V]{b.root}
+	for len(level) > 0 {
+		stats.NodesPerLevel = append(stats.NodesPerLevel, len(level))
+		var below []*bNode[K, V]
+		for _, node := range level {
+			if !node.leaf {
+				below = append(below, node.children...)
+				continue
+			}
+			fill := float64(len(node.keys)) / capacity
+			if fill < stats.MinLeafFill {
+				stats.MinLeafFill = fill
+			}
+			stats.Keys += len(node.keys)
+			stats.Leaves++
+		}
+		level = below
+	}
+	stats.Height = len(stats.NodesPerLevel)
+	stats.AvgLeafFill = float64(stats.Keys) / (capacity * float64(stats.Leaves))
+	return stats
+}
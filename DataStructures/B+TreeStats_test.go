@@ -0,0 +1,79 @@
+package DataStructures
+
+import (
+	"testing"
+)
+
+func TestStatsEmpty(t *testing.T) {
+	stats := newBTree[int, int](4).Stats()
+	if stats.Height != 1 || len(stats.NodesPerLevel) != 1 || stats.Leaves != 1 || stats.Keys != 0 {
+		t.Errorf("empty tree stats: %+v", stats)
+	}
+	if stats.AvgLeafFill != 0 || stats.MinLeafFill != 0 {
+		t.Errorf("empty tree fill: avg %v min %v", stats.AvgLeafFill, stats.MinLeafFill)
+	}
+	rootless := newBTree[int, int](3)
+	rootless.root = nil
+	if stats := rootless.Stats(); stats.Height != 0 {
+		t.Errorf("tree without a root has height %d", stats.Height)
+	}
+}
+
+func TestStatsShape(t *testing.T) {
+	keys := make([]int, 100)
+	for i := range keys {
+		keys[i] = i
+	}
+	btree := newBTree[int, int](5)
+	if err := btree.BulkLoad(mustSliceIterator[int](t, keys, nil), 1); err != nil {
+		t.Fatal(err)
+	}
+	stats := btree.Stats()
+	// 100 keys in full leaves of 4, then 25 leaves under 5 parents under the root
+	want := []int{1, 5, 25}
+	if !equalKeys(stats.NodesPerLevel, want) || stats.Height != 3 {
+		t.Fatalf("NodesPerLevel = %v, height %d, want %v", stats.NodesPerLevel, stats.Height, want)
+	}
+	if stats.Keys != 100 || stats.Leaves != 25 {
+		t.Errorf("Keys = %d, Leaves = %d", stats.Keys, stats.Leaves)
+	}
+	if stats.AvgLeafFill != 1 || stats.MinLeafFill != 1 {
+		t.Errorf("full leaves report fill avg %v min %v", stats.AvgLeafFill, stats.MinLeafFill)
+	}
+	if stats.Splits != 0 || stats.Merges != 0 {
+		t.Errorf("bulk load counted %d splits and %d merges", stats.Splits, stats.Merges)
+	}
+}
+
+func TestStatsCounters(t *testing.T) {
+	btree := newBTree[int, int](4)
+	for k := 0; k < 500; k++ {
+		btree.Insert(k)
+	}
+	stats := btree.Stats()
+	nodes := 0
+	for _, n := range stats.NodesPerLevel {
+		nodes += n
+	}
+	// Every split adds one node, and growing a level adds a new root on top
+	if want := 1 + int(stats.Splits) + stats.Height - 1; nodes != want {
+		t.Errorf("%d nodes after %d splits and height %d, want %d", nodes, stats.Splits, stats.Height, want)
+	}
+	if stats.Keys != 500 || stats.Merges != 0 {
+		t.Errorf("Keys = %d, Merges = %d", stats.Keys, stats.Merges)
+	}
+	if stats.MinLeafFill <= 0 || stats.MinLeafFill > stats.AvgLeafFill || stats.AvgLeafFill > 1 {
+		t.Errorf("fill out of order: min %v avg %v", stats.MinLeafFill, stats.AvgLeafFill)
+	}
+
+	for k := 0; k < 400; k++ {
+		btree.Delete(k)
+	}
+	after := btree.Stats()
+	if after.Merges == 0 || after.Splits != stats.Splits {
+		t.Errorf("after deletes: %d merges, %d splits (was %d)", after.Merges, after.Splits, stats.Splits)
+	}
+	if after.Keys != 100 {
+		t.Errorf("Keys = %d after deletes, want 100", after.Keys)
+	}
+}
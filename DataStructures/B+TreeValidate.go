@@ -0,0 +1,170 @@
+package DataStructures
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationError describes the first broken invariant Validate found
+type ValidationError struct {
+	Path   []int  // child indexes from the root down to the offending node
+	Rule   string // which invariant was broken
+	Detail string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("btree: %s at %s: %s", e.Rule, formatNodePath(e.Path), e.Detail)
+}
+
+// formatNodePath renders a node path as root/0/2
+func formatNodePath(path []int) string {
+	parts := []string{"root"}
+	for _, i := range path {
+		parts = append(parts, fmt.Sprintf("%d", i))
+	}
+	return strings.Join(parts, "/")
+}
+
+// keyBound is an optional bound on the keys allowed in a subtree
+type keyBound[K any] struct {
+	set bool
+	key K
+}
+
+// Validate walks the whole tree and checks its structural invariants:
+// key ordering, separator bounds, node fill against 'degree', uniform leaf depth,
+// parent/child back-pointers, subtree counts (if kept) and a complete leaf chain in both directions.
+// It returns a *ValidationError for the first violation, or nil
+func (b *BTree[K, V]) Validate() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.root == nil {
+		return nil
+	}
+	if b.root.parent != nil {
+		return &ValidationError{Rule: "parent pointer", Detail: "root has a parent"}
+	}
+
+	v := &validator[K, V]{tree: b, leafDepth: -1}
+	if _, err := v.walk(b.root, nil, 0, keyBound[K]{}, keyBound[K]{}); err != nil {
+		return err
+	}
+	return v.checkLeafChain()
+}
+
+// validator carries state across the recursive walk
+type validator[K any, V any] struct {
+	tree      *BTree[K, V]
+	leafDepth int
+	leaves    []*bNode[K, V] // leaves in key order, as found by the walk
+	paths     [][]int        // path of each leaf in 'leaves'
+}
+
+// walk checks the subtree under 'node' and returns how many keys it holds
+func (v *validator[K, V]) walk(node *bNode[K, V], path []int, depth int, lo, hi keyBound[K]) (int, error) {
+	fail := func(rule, format string, args ...any) (int, error) {
+		return 0, &ValidationError{Path: append([]int{}, path...), Rule: rule, Detail: fmt.Sprintf(format, args...)}
+	}
+
+	// Fill limits
+	if node.overFill(v.tree.degree) {
+		return fail("node fill", "%d keys, degree %d allows at most %d", len(node.keys), v.tree.degree, v.tree.degree-1)
+	}
+	if node != v.tree.root && node.underFill(v.tree.degree) {
+		return fail("node fill", "%d keys, degree %d needs at least %d", len(node.keys), v.tree.degree, minKeys(v.tree.degree))
+	}
+
+	// Ordering and separator bounds: lo <= key < hi
+	for i, key := range node.keys {
+		if i > 0 && v.tree.compare(node.keys[i-1], key) >= 0 {
+			return fail("key order", "keys[%d]=%v is not greater than keys[%d]=%v", i, key, i-1, node.keys[i-1])
+		}
+		if lo.set && v.tree.compare(key, lo.key) < 0 {
+			return fail("separator bound", "key %v is below the lower separator %v", key, lo.key)
+		}
+		if hi.set && v.tree.compare(key, hi.key) >= 0 {
+			return fail("separator bound", "key %v is not below the upper separator %v", key, hi.key)
+		}
+	}
+
+	if node.leaf {
+		if len(node.children) != 0 {
+			return fail("leaf shape", "leaf has %d children", len(node.children))
+		}
+		if len(node.values) != len(node.keys) {
+			return fail("leaf shape", "%d keys but %d values", len(node.keys), len(node.values))
+		}
+		if v.leafDepth == -1 {
+			v.leafDepth = depth
+		} else if depth != v.leafDepth {
+			return fail("leaf depth", "leaf at depth %d, expected %d", depth, v.leafDepth)
+		}
+		v.leaves = append(v.leaves, node)
+		v.paths = append(v.paths, append([]int{}, path...))
+		return len(node.keys), nil
+	}
+
+	if len(node.keys) == 0 {
+		return fail("node fill", "internal node has no keys")
+	}
+	if len(node.children) != len(node.keys)+1 {
+		return fail("internal shape", "%d keys but %d children", len(node.keys), len(node.children))
+	}
+	if len(node.values) != 0 {
+		return fail("internal shape", "internal node holds %d values", len(node.values))
+	}
+	counted := v.tree.counted
+	if counted && len(node.counts) != len(node.children) {
+		return fail("internal shape", "%d children but %d counts", len(node.children), len(node.counts))
+	}
+	if !counted && node.counts != nil {
+		return fail("internal shape", "%d counts in a tree that keeps none", len(node.counts))
+	}
+
+	total := 0
+	for i, child := range node.children {
+		if child == nil {
+			return fail("internal shape", "children[%d] is nil", i)
+		}
+		if child.parent != node {
+			return fail("parent pointer", "children[%d] does not point back to this node", i)
+		}
+		childLo, childHi := lo, hi
+		if i > 0 {
+			childLo = keyBound[K]{set: true, key: node.keys[i-1]}
+		}
+		if i < len(node.keys) {
+			childHi = keyBound[K]{set: true, key: node.keys[i]}
+		}
+		count, err := v.walk(child, append(path, i), depth+1, childLo, childHi)
+		if err != nil {
+			return 0, err
+		}
+		if counted && node.counts[i] != count {
+			return fail("subtree count", "counts[%d]=%d but the child holds %d keys", i, node.counts[i], count)
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// checkLeafChain makes sure following 'next' and 'prev' visits exactly the leaves the walk found
+func (v *validator[K, V]) checkLeafChain() error {
+	for i, leaf := range v.leaves {
+		var wantNext, wantPrev *bNode[K, V]
+		if i+1 < len(v.leaves) {
+			wantNext = v.leaves[i+1]
+		}
+		if i > 0 {
+			wantPrev = v.leaves[i-1]
+		}
+		if leaf.next != wantNext {
+			return &ValidationError{Path: v.paths[i], Rule: "leaf chain", Detail: "next does not point at the following leaf"}
+		}
+		if leaf.prev != wantPrev {
+			return &ValidationError{Path: v.paths[i], Rule: "leaf chain", Detail: "prev does not point at the preceding leaf"}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package DataStructures
+
+import (
+	"errors"
+	"testing"
+)
+
+// buildValidTree returns a tree that is exactly three levels deep
+func buildValidTree(t *testing.T) *BTree[int, int] {
+	btree := newBTree[int, int](4)
+	for i := 0; i < 16; i++ {
+		btree.Put(i, i)
+	}
+	if err := btree.Validate(); err != nil {
+		t.Fatalf("Freshly built tree failed validation: %v", err)
+	}
+	if btree.root.leaf || btree.root.children[0].leaf || !btree.root.children[0].children[0].leaf {
+		t.Fatalf("Expected a tree of three levels")
+	}
+	return btree
+}
+
+// expectViolation checks Validate reports 'rule' at 'path'
+func expectViolation(t *testing.T, btree *BTree[int, int], rule string, path []int) {
+	t.Helper()
+	err := btree.Validate()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected a *ValidationError for %q, got %v", rule, err)
+	}
+	if verr.Rule != rule {
+		t.Errorf("Expected rule %q, got %q (%v)", rule, verr.Rule, err)
+	}
+	if formatNodePath(verr.Path) != formatNodePath(path) {
+		t.Errorf("Expected path %s, got %s", formatNodePath(path), formatNodePath(verr.Path))
+	}
+}
+
+func TestValidateEmptyTree(t *testing.T) {
+	if err := newBTree[int, int](4).Validate(); err != nil {
+		t.Errorf("Empty tree should be valid, got %v", err)
+	}
+	rootless := newBTree[int, int](3)
+	rootless.root = nil
+	if err := rootless.Validate(); err != nil {
+		t.Errorf("Tree without a root should be valid, got %v", err)
+	}
+}
+
+func TestValidateDetectsCorruption(t *testing.T) {
+	t.Run("key order", func(t *testing.T) {
+		btree := buildValidTree(t)
+		leaf := btree.root.children[0].children[0]
+		leaf.keys[0], leaf.keys[1] = leaf.keys[1], leaf.keys[0]
+		expectViolation(t, btree, "key order", []int{0, 0})
+	})
+
+	t.Run("separator bound", func(t *testing.T) {
+		btree := buildValidTree(t)
+		btree.root.children[1].children[0].keys[0] = -1
+		expectViolation(t, btree, "separator bound", []int{1, 0})
+	})
+
+	t.Run("stale parent pointer", func(t *testing.T) {
+		btree := buildValidTree(t)
+		btree.root.children[1].children[1].parent = btree.root.children[0]
+		expectViolation(t, btree, "parent pointer", []int{1})
+	})
+
+	t.Run("broken next chain", func(t *testing.T) {
+		btree := buildValidTree(t)
+		leaf := btree.root.children[0].children[1]
+		leaf.next = leaf.next.next
+		expectViolation(t, btree, "leaf chain", []int{0, 1})
+	})
+
+	t.Run("broken prev chain", func(t *testing.T) {
+		btree := buildValidTree(t)
+		leaf := btree.root.children[1].children[0]
+		leaf.prev = nil
+		expectViolation(t, btree, "leaf chain", []int{1, 0})
+	})
+
+	t.Run("overfilled node", func(t *testing.T) {
+		btree := buildValidTree(t)
+		leaf := btree.leftmostLeaf()
+		for len(leaf.keys) < int(btree.degree) {
+			leaf.keys = append([]int{leaf.keys[0] - 1}, leaf.keys...)
+			leaf.values = append(leaf.values, 0)
+		}
+		expectViolation(t, btree, "node fill", []int{0, 0})
+	})
+
+	t.Run("uneven leaf depth", func(t *testing.T) {
+		btree := buildValidTree(t)
+		// Hang the last leaf directly off the root
+		last := btree.root.children[len(btree.root.children)-1]
+		leaf := last.children[len(last.children)-1]
+		btree.root.children[len(btree.root.children)-1] = leaf
+		leaf.parent = btree.root
+		expectViolation(t, btree, "leaf depth", []int{len(btree.root.children) - 1})
+	})
+
+	t.Run("missing values", func(t *testing.T) {
+		btree := buildValidTree(t)
+		leaf := btree.rightmostLeaf()
+		leaf.values = leaf.values[:len(leaf.values)-1]
+		expectViolation(t, btree, "leaf shape", []int{len(btree.root.children) - 1, len(btree.rightmostLeaf().parent.children) - 1})
+	})
+}
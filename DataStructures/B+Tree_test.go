@@ -1,18 +1,24 @@
 package DataStructures
 
 import (
+	"errors"
+	"fmt"
+	"math/rand"
 	"testing"
 )
 
 // TestInsert validates insert functionality.
 func TestInsert(t *testing.T) {
-	btree := newBTree[int](4)
+	btree := newBTree[int, int](4)
 
 	// Insert elements and check tree structure.
 	values := []int{10, 20, 5, 6, 15, 30, 25, 35}
 	for _, v := range values {
 		//fmt.Println("attempting to insert ", v)
 		btree.Insert(v)
+		if err := btree.Validate(); err != nil {
+			t.Fatalf("Insert(%d) broke the tree: %v", v, err)
+		}
 	}
 	btree.Display()
 
@@ -31,7 +37,7 @@ func TestInsert(t *testing.T) {
 	}
 }
 func TestEmptyTreeInsert(t *testing.T) {
-	tree := newBTree[int](4) //into an empty tree
+	tree := newBTree[int, int](4) //into an empty tree
 	tree.Insert(10)
 
 	// Verify root contains the inserted key
@@ -41,7 +47,7 @@ func TestEmptyTreeInsert(t *testing.T) {
 }
 
 func TestEmptyTreeSearch(t *testing.T) {
-	tree := newBTree[int](4)
+	tree := newBTree[int, int](4)
 
 	// n an empty tree
 	if tree.Search(10) {
@@ -51,20 +57,26 @@ func TestEmptyTreeSearch(t *testing.T) {
 
 // TestDelete validates delete functionality.
 func TestDelete(t *testing.T) {
-	btree := &BTree[int]{degree: 3}
+	btree := newBTree[int, int](3)
 
 	// Insert elements.
 	values := []int{10, 20, 5, 6, 15, 30, 25, 35}
 	for _, v := range values {
 		btree.Insert(v)
+		if err := btree.Validate(); err != nil {
+			t.Fatalf("Insert(%d) broke the tree: %v", v, err)
+		}
 	}
 
 	// Delete some elements.
 	toDelete := []int{6, 15, 25}
 	for _, v := range toDelete {
-		if !btree.Delete(v) {
+		if _, ok := btree.Delete(v); !ok {
 			t.Errorf("Delete failed: %d not deleted from tree", v)
 		}
+		if err := btree.Validate(); err != nil {
+			t.Fatalf("Delete(%d) broke the tree: %v", v, err)
+		}
 	}
 
 	// Verify deleted elements are absent.
@@ -87,13 +99,13 @@ func TestDelete(t *testing.T) {
 
 // TestEdgeCases tests edge cases like empty trees and single-node trees.
 func TestEdgeCases(t *testing.T) {
-	btree := &BTree[int]{degree: 3}
+	btree := newBTree[int, int](3)
 
 	// Test search and delete on empty tree.
 	if btree.Search(10) {
 		t.Errorf("Search failed: found value in an empty tree")
 	}
-	if btree.Delete(10) {
+	if _, ok := btree.Delete(10); ok {
 		t.Errorf("Delete failed: deleted value from an empty tree")
 	}
 
@@ -106,7 +118,7 @@ func TestEdgeCases(t *testing.T) {
 	}
 
 	// Delete the single element.
-	if !btree.Delete(10) {
+	if _, ok := btree.Delete(10); !ok {
 		t.Errorf("Delete failed: 10 not deleted from tree")
 	}
 
@@ -117,7 +129,7 @@ func TestEdgeCases(t *testing.T) {
 }
 
 func TestDisplay(t *testing.T) {
-	btree := &BTree[int]{degree: 4}
+	btree := newBTree[int, int](4)
 
 	// Insert values.
 	values := []int{50, 20, 70, 10, 30, 60, 80, 90, 40}
@@ -129,13 +141,238 @@ func TestDisplay(t *testing.T) {
 	btree.Display()
 }
 
+// TestPutGet validates that values are stored with their keys and overwritten by Put.
+func TestPutGet(t *testing.T) {
+	btree := newBTree[int, string](4)
+
+	for i := 0; i < 50; i++ {
+		btree.Put(i, fmt.Sprintf("row-%d", i))
+	}
+	for i := 0; i < 50; i++ {
+		v, ok := btree.Get(i)
+		if !ok || v != fmt.Sprintf("row-%d", i) {
+			t.Errorf("Get(%d) = (%q, %v), expected (%q, true)", i, v, ok, fmt.Sprintf("row-%d", i))
+		}
+	}
+
+	// Put overwrites, Insert leaves existing values alone
+	btree.Put(7, "updated")
+	btree.Insert(7)
+	if v, _ := btree.Get(7); v != "updated" {
+		t.Errorf("Put did not overwrite: expected %q, got %q", "updated", v)
+	}
+	if count := len(leafKeys(btree)); count != 50 {
+		t.Errorf("Overwrite changed entry count: expected 50, got %d", count)
+	}
+
+	if _, ok := btree.Get(100); ok {
+		t.Errorf("Get(100) found a key that was never inserted")
+	}
+}
+
+// TestInsertUnique validates that duplicates are reported instead of ignored.
+func TestInsertUnique(t *testing.T) {
+	btree := newBTree[int, string](3)
+	for i := 0; i < 30; i++ {
+		if err := btree.InsertUnique(i); err != nil {
+			t.Fatalf("InsertUnique(%d) = %v", i, err)
+		}
+	}
+	btree.Put(7, "seven")
+	err := btree.InsertUnique(7)
+	if !errors.Is(err, ErrDuplicateKey) {
+		t.Fatalf("InsertUnique(7) = %v, expected ErrDuplicateKey", err)
+	}
+	if err.Error() != "btree: duplicate key: 7" {
+		t.Errorf("error does not name the key: %q", err)
+	}
+	if v, _ := btree.Get(7); v != "seven" {
+		t.Errorf("a rejected InsertUnique changed the value to %q", v)
+	}
+	if count := len(leafKeys(btree)); count != 30 {
+		t.Errorf("expected 30 keys, got %d", count)
+	}
+}
+
+// TestUpsert validates that Upsert hands back what it replaced.
+func TestUpsert(t *testing.T) {
+	btree := newBTree[int, string](3)
+	for i := 0; i < 30; i++ {
+		if old, replaced := btree.Upsert(i, fmt.Sprint("a", i)); replaced || old != "" {
+			t.Fatalf("Upsert(%d) on a new key = (%q, %v)", i, old, replaced)
+		}
+	}
+	for i := 0; i < 30; i++ {
+		if old, replaced := btree.Upsert(i, fmt.Sprint("b", i)); !replaced || old != fmt.Sprint("a", i) {
+			t.Fatalf("Upsert(%d) = (%q, %v), expected (%q, true)", i, old, replaced, fmt.Sprint("a", i))
+		}
+		if v, _ := btree.Get(i); v != fmt.Sprint("b", i) {
+			t.Fatalf("Get(%d) = %q after Upsert", i, v)
+		}
+	}
+	if err := btree.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	rootless := newBTree[int, string](3)
+	rootless.root = nil
+	if old, replaced := rootless.Upsert(1, "x"); replaced || old != "" {
+		t.Errorf("Upsert into a tree without a root = (%q, %v)", old, replaced)
+	}
+}
+
+// TestDeleteReturnsValue validates that Delete hands back the removed payload.
+func TestDeleteReturnsValue(t *testing.T) {
+	btree := newBTree[int, string](3)
+	btree.Put(1, "one")
+	btree.Put(2, "two")
+	btree.Put(3, "three")
+
+	v, ok := btree.Delete(2)
+	if !ok || v != "two" {
+		t.Errorf("Delete(2) = (%q, %v), expected (\"two\", true)", v, ok)
+	}
+	if v, ok := btree.Delete(2); ok || v != "" {
+		t.Errorf("Second Delete(2) = (%q, %v), expected (\"\", false)", v, ok)
+	}
+}
+
+// TestValuesSurviveSplitAndMerge runs random puts and deletes against a map.
+func TestValuesSurviveSplitAndMerge(t *testing.T) {
+	for _, degree := range []uint16{3, 4, 5, 8} {
+		rng := rand.New(rand.NewSource(int64(degree)))
+		btree := newBTree[int, int](degree)
+		expected := make(map[int]int)
+
+		for op := 0; op < 2000; op++ {
+			k := rng.Intn(300)
+			if rng.Intn(3) == 0 {
+				v, ok := btree.Delete(k)
+				want, exists := expected[k]
+				if ok != exists || v != want {
+					t.Fatalf("degree %d: Delete(%d) = (%d, %v), expected (%d, %v)", degree, k, v, ok, want, exists)
+				}
+				delete(expected, k)
+			} else {
+				btree.Put(k, op)
+				expected[k] = op
+			}
+			if err := btree.Validate(); err != nil {
+				t.Fatalf("degree %d: op %d on key %d broke the tree: %v", degree, op, k, err)
+			}
+		}
+
+		keys := leafKeys(btree)
+		if len(keys) != len(expected) {
+			t.Fatalf("degree %d: leaf chain holds %d keys, expected %d", degree, len(keys), len(expected))
+		}
+		for i, k := range keys {
+			if i > 0 && keys[i-1] >= k {
+				t.Fatalf("degree %d: leaf chain out of order at %d: %v", degree, i, keys)
+			}
+			if v, ok := btree.Get(k); !ok || v != expected[k] {
+				t.Errorf("degree %d: Get(%d) = (%d, %v), expected (%d, true)", degree, k, v, ok, expected[k])
+			}
+		}
+	}
+}
+
+// TestDeleteKeepsDegreeBounds deletes keys in random order and checks every node
+// stays within [minKeys, degree-1] keys after each delete.
+func TestDeleteKeepsDegreeBounds(t *testing.T) {
+	for _, degree := range []uint16{3, 4, 5, 6, 9} {
+		for seed := int64(0); seed < 5; seed++ {
+			rng := rand.New(rand.NewSource(seed))
+			btree := newBTree[int, int](degree)
+			keys := rng.Perm(400)
+			for _, k := range keys {
+				btree.Put(k, k)
+			}
+
+			rng.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+			for i, k := range keys {
+				if _, ok := btree.Delete(k); !ok {
+					t.Fatalf("degree %d seed %d: Delete(%d) not found", degree, seed, k)
+				}
+				if err := checkDegreeBounds(btree); err != "" {
+					t.Fatalf("degree %d seed %d: after deleting %d (%d of %d): %s", degree, seed, k, i+1, len(keys), err)
+				}
+				if err := btree.Validate(); err != nil {
+					t.Fatalf("degree %d seed %d: after deleting %d: %v", degree, seed, k, err)
+				}
+				// Interleave some inserts so borrowing and merging happen in mixed workloads
+				if i%7 == 0 {
+					btree.Put(k+1000, k)
+					btree.Delete(k + 1000)
+				}
+			}
+			if len(leafKeys(btree)) != 0 {
+				t.Fatalf("degree %d seed %d: tree not empty after deleting every key", degree, seed)
+			}
+		}
+	}
+}
+
+// TestDeleteBorrowsFromSibling checks a delete that can borrow does not merge.
+func TestDeleteBorrowsFromSibling(t *testing.T) {
+	btree := newBTree[int, int](4)
+	for _, v := range []int{10, 20, 30, 40, 50} {
+		btree.Insert(v)
+	}
+	// Leaves: [10 20] [30 40 50], removing 10 and 20 empties the left leaf
+	// while the right leaf has a key to spare
+	btree.Delete(10)
+	btree.Delete(20)
+
+	if btree.root.leaf || len(btree.root.children) != 2 {
+		t.Fatalf("Expected the root to keep two leaves after borrowing")
+	}
+	left, right := btree.root.children[0], btree.root.children[1]
+	if !equalKeys(left.keys, []int{30}) || !equalKeys(right.keys, []int{40, 50}) {
+		t.Errorf("Expected leaves [30] [40 50], got %v %v", left.keys, right.keys)
+	}
+	if btree.root.keys[0] != 40 {
+		t.Errorf("Expected separator 40 after borrowing, got %d", btree.root.keys[0])
+	}
+}
+
 // TestRandomized validates random insertions and deletions.
+func TestRandomized(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		degree := uint16(3 + rng.Intn(8))
+		btree := newBTree[int, int](degree)
+		present := make(map[int]bool)
+
+		for op := 0; op < 1500; op++ {
+			k := rng.Intn(200)
+			if rng.Intn(2) == 0 {
+				btree.Insert(k)
+				present[k] = true
+			} else {
+				_, ok := btree.Delete(k)
+				if ok != present[k] {
+					t.Fatalf("seed %d: Delete(%d) reported %v, expected %v", seed, k, ok, present[k])
+				}
+				delete(present, k)
+			}
+			if err := btree.Validate(); err != nil {
+				t.Fatalf("seed %d degree %d: op %d on key %d: %v", seed, degree, op, k, err)
+			}
+		}
+		for k := 0; k < 200; k++ {
+			if btree.Search(k) != present[k] {
+				t.Fatalf("seed %d: Search(%d) = %v, expected %v", seed, k, !present[k], present[k])
+			}
+		}
+	}
+}
 
 // TestDisplay ensures the display function works without errors.
 // Helper function: count occurrences of a value in the tree.
-func countOccurrences(btree *BTree[int], value int) int {
+// Only leaves hold entries, internal nodes keep copies of keys as separators
+func countOccurrences(btree *BTree[int, int], value int) int {
 	count := 0
-	queue := NewQueue[*bNode[int]]()
+	queue := NewQueue[*bNode[int, int]]()
 	queue.Enqueue(btree.root)
 
 	for !queue.IsEmpty() {
@@ -144,9 +381,11 @@ func countOccurrences(btree *BTree[int], value int) int {
 			continue
 		}
 
-		for _, key := range node.keys {
-			if key == value {
-				count++
+		if node.leaf {
+			for _, key := range node.keys {
+				if key == value {
+					count++
+				}
 			}
 		}
 
@@ -157,3 +396,41 @@ func countOccurrences(btree *BTree[int], value int) int {
 
 	return count
 }
+
+// Helper function: collect keys by walking the leaf chain from the leftmost leaf.
+func leafKeys[V any](btree *BTree[int, V]) []int {
+	var keys []int
+	if btree.root == nil {
+		return keys
+	}
+	node := btree.root
+	for !node.leaf {
+		node = node.children[0]
+	}
+	for ; node != nil; node = node.next {
+		keys = append(keys, node.keys...)
+	}
+	return keys
+}
+
+// Helper function: report the first node outside its degree bounds, or "" if all are fine.
+func checkDegreeBounds[V any](btree *BTree[int, V]) string {
+	queue := NewQueue[*bNode[int, V]]()
+	queue.Enqueue(btree.root)
+	for !queue.IsEmpty() {
+		node := queue.Dequeue()
+		if node.overFill(btree.degree) {
+			return fmt.Sprintf("node %v is overfilled", node.keys)
+		}
+		if node != btree.root && node.underFill(btree.degree) {
+			return fmt.Sprintf("node %v is underfilled", node.keys)
+		}
+		if !node.leaf && len(node.children) != len(node.keys)+1 {
+			return fmt.Sprintf("node %v has %d children", node.keys, len(node.children))
+		}
+		for _, child := range node.children {
+			queue.Enqueue(child)
+		}
+	}
+	return ""
+}
@@ -4,7 +4,10 @@ import "fmt"
 
 // Exact same as constraints.Ordered but Doesnt require me to update the go Version
 type Ordered interface {
-	~int | ~float64 | ~string
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64 |
+		~string
 }
 
 type node[T Ordered] struct {
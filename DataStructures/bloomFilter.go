@@ -1,18 +1,24 @@
 package DataStructures
 
 import (
-	"hash"
-	"hash/fnv"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"math"
+	"math/bits"
+	"sync/atomic"
 )
 
-// BloomFilter represents a Bloom filter data structure
+// BloomFilter represents a Bloom filter data structure. Add and Contains may
+// be called from any number of goroutines at once, bits are set atomically
+// and hashing keeps no state. UnmarshalBinary and Intersect are the
+// exceptions, they need the filter to themselves
 type BloomFilter struct {
-	bitArray     []bool
-	size         uint
+	bitArray     []uint64 // bit i is bit i%64 of word i/64, read and written atomically
+	size         uint     // number of bits in use
 	hashCount    uint
-	hashFunc     hash.Hash64
-	elementCount uint
+	seed         uint64 // mixed into every hash, filters only agree on positions if their seeds match
+	elementCount atomic.Uint64
 }
 type Exister interface {
 	Add(d []byte)
@@ -25,18 +31,27 @@ func cache(size uint, FalsePositve float64) Exister {
 
 // NewBloomFilter creates a new Bloom filter with the given parameters
 func NewBloomFilter(expectedElements uint, falsePositiveRate float64) *BloomFilter {
+	return NewBloomFilterWithSeed(expectedElements, falsePositiveRate, 0)
+}
+
+// NewBloomFilterWithSeed is NewBloomFilter with its hashes seeded by 'seed'
+func NewBloomFilterWithSeed(expectedElements uint, falsePositiveRate float64, seed uint64) *BloomFilter {
 	size := calculateSize(expectedElements, falsePositiveRate)
 	hashCount := calculateHashCount(size, expectedElements)
 
 	return &BloomFilter{
-		bitArray:     make([]bool, size),
-		size:         size,
-		hashCount:    hashCount,
-		hashFunc:     fnv.New64(),
-		elementCount: 0,
+		bitArray:  make([]uint64, bitsetWords(size)),
+		size:      size,
+		hashCount: hashCount,
+		seed:      seed,
 	}
 }
 
+// bitsetWords is how many uint64 words hold 'size' bits
+func bitsetWords(size uint) uint {
+	return (size + 63) / 64
+}
+
 // calculateSize determines the optimal size of the bit array
 func calculateSize(n uint, p float64) uint {
 	m := -(float64(n) * math.Log(p)) / math.Pow(math.Log(2), 2)
@@ -46,35 +61,77 @@ func calculateSize(n uint, p float64) uint {
 // calculateHashCount determines the optimal number of hash functions
 func calculateHashCount(m, n uint) uint {
 	k := (float64(m) / float64(n)) * math.Log(2)
-	return uint(math.Ceil(k))
+	return min(uint(math.Ceil(k)), maxHashCount)
 }
 
+// maxHashCount bounds the hashes per item. 64 already gives a false positive
+// rate far below 1e-18, more only slows Add and Contains down
+const maxHashCount = 64
+
 // getHashValues generates multiple hash values for an item
 func (bf *BloomFilter) getHashValues(item []byte) []uint {
-	bf.hashFunc.Reset()
-	bf.hashFunc.Write(item)
-	h64 := bf.hashFunc.Sum64()
+	return hashPositions(bf.seed, item, bf.hashCount, bf.size)
+}
+
+// FNV-1 parameters, see hash/fnv
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
 
-	hashValues := make([]uint, bf.hashCount)
-	for i := uint(0); i < bf.hashCount; i++ {
+// seededFNV is 64 bit FNV-1 of the seed, little endian, followed by 'item'.
+// It gives what hash/fnv would but keeps its state on the stack, so any
+// number of goroutines can hash at once
+func seededFNV(seed uint64, item []byte) uint64 {
+	h := uint64(fnvOffset64)
+	for i := 0; i < 8; i++ {
+		h *= fnvPrime64
+		h ^= seed >> (8 * i) & 0xFF
+	}
+	for _, c := range item {
+		h *= fnvPrime64
+		h ^= uint64(c)
+	}
+	return h
+}
+
+// hashPositions hashes 'item' once and derives 'k' positions below 'm'
+func hashPositions(seed uint64, item []byte, k, m uint) []uint {
+	h64 := seededFNV(seed, item)
+
+	hashValues := make([]uint, k)
+	for i := uint(0); i < k; i++ {
 		// Use double hashing to generate multiple hash values
-		hashValues[i] = uint((h64 + uint64(i)*uint64(h64>>32)) % uint64(bf.size))
+		hashValues[i] = uint((h64 + uint64(i)*uint64(h64>>32)) % uint64(m))
 	}
 	return hashValues
 }
 
+// setBit turns on bit 'pos'
+func (bf *BloomFilter) setBit(pos uint) {
+	mask := uint64(1) << (pos % 64)
+	if atomic.LoadUint64(&bf.bitArray[pos/64])&mask == 0 {
+		updateWord(&bf.bitArray[pos/64], func(w uint64) uint64 { return w | mask })
+	}
+}
+
+// hasBit reports whether bit 'pos' is on
+func (bf *BloomFilter) hasBit(pos uint) bool {
+	return atomic.LoadUint64(&bf.bitArray[pos/64])&(1<<(pos%64)) != 0
+}
+
 // Add adds an item to the Bloom filter
 func (bf *BloomFilter) Add(item []byte) {
 	for _, pos := range bf.getHashValues(item) {
-		bf.bitArray[pos] = true
+		bf.setBit(pos)
 	}
-	bf.elementCount++
+	bf.elementCount.Add(1)
 }
 
 // Contains checks if an item might be in the set
 func (bf *BloomFilter) Contains(item []byte) bool {
 	for _, pos := range bf.getHashValues(item) {
-		if !bf.bitArray[pos] {
+		if !bf.hasBit(pos) {
 			return false
 		}
 	}
@@ -84,19 +141,20 @@ func (bf *BloomFilter) Contains(item []byte) bool {
 // CurrentFalsePositiveRate calculates the current false positive rate
 func (bf *BloomFilter) CurrentFalsePositiveRate() float64 {
 	filledBits := 0
-	for _, bit := range bf.bitArray {
-		if bit {
-			filledBits++
-		}
+	for i := range bf.bitArray {
+		filledBits += bits.OnesCount64(atomic.LoadUint64(&bf.bitArray[i]))
 	}
 
+	// The fill ratio already is the chance one bit is set, an absent item
+	// tests present when all of its bits happen to be
 	fillRatio := float64(filledBits) / float64(bf.size)
-	return math.Pow(1-math.Exp(-float64(bf.hashCount)*fillRatio), float64(bf.hashCount))
+	return math.Pow(fillRatio, float64(bf.hashCount))
 }
 
-// ElementCount returns the number of elements added to the filter
+// ElementCount returns the number of elements added to the filter, including
+// those added to filters merged in with Union. Repeated items count each time
 func (bf *BloomFilter) ElementCount() uint {
-	return bf.elementCount
+	return uint(bf.elementCount.Load())
 }
 
 // Size returns the size of the bit array
@@ -108,3 +166,70 @@ func (bf *BloomFilter) Size() uint {
 func (bf *BloomFilter) HashCount() uint {
 	return bf.hashCount
 }
+
+// Seed returns the seed mixed into the filter's hashes
+func (bf *BloomFilter) Seed() uint64 {
+	return bf.seed
+}
+
+// The binary form of a BloomFilter is a header followed by the bitset, every
+// number big endian:
+//
+//	magic        [4]byte "BLM1"
+//	size         uint64  bits
+//	hashCount    uint32
+//	elementCount uint64
+//	seed         uint64
+//	bitset       (size+63)/64 uint64 words
+var bloomMagic = [4]byte{'B', 'L', 'M', '1'}
+
+const bloomHeaderSize = 4 + 8 + 4 + 8 + 8
+
+var ErrCorruptFilter = errors.New("bloom: corrupt filter encoding")
+
+// MarshalBinary implements encoding.BinaryMarshaler. Items added while it
+// runs may or may not make it into the output
+func (bf *BloomFilter) MarshalBinary() ([]byte, error) {
+	out := make([]byte, bloomHeaderSize, bloomHeaderSize+8*len(bf.bitArray))
+	copy(out, bloomMagic[:])
+	binary.BigEndian.PutUint64(out[4:], uint64(bf.size))
+	binary.BigEndian.PutUint32(out[12:], uint32(bf.hashCount))
+	binary.BigEndian.PutUint64(out[16:], bf.elementCount.Load())
+	binary.BigEndian.PutUint64(out[24:], bf.seed)
+	for i := range bf.bitArray {
+		out = binary.BigEndian.AppendUint64(out, atomic.LoadUint64(&bf.bitArray[i]))
+	}
+	return out, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, replacing the
+// filter with the one in 'data'
+func (bf *BloomFilter) UnmarshalBinary(data []byte) error {
+	if len(data) < bloomHeaderSize || [4]byte(data[:4]) != bloomMagic {
+		return fmt.Errorf("%w: bad header", ErrCorruptFilter)
+	}
+	size := binary.BigEndian.Uint64(data[4:])
+	hashCount := binary.BigEndian.Uint32(data[12:])
+	if size == 0 || hashCount == 0 || hashCount > maxHashCount || uint64(hashCount) > size {
+		return fmt.Errorf("%w: %d bits and %d hashes", ErrCorruptFilter, size, hashCount)
+	}
+	words := data[bloomHeaderSize:]
+	if size > 8*uint64(len(words)) || uint64(len(words)) != 8*((size+63)/64) {
+		return fmt.Errorf("%w: %d bytes of bitset for %d bits", ErrCorruptFilter, len(words), size)
+	}
+
+	bitArray := make([]uint64, len(words)/8)
+	for i := range bitArray {
+		bitArray[i] = binary.BigEndian.Uint64(words[8*i:])
+	}
+	// Bits past 'size' are never set
+	if tail := size % 64; tail != 0 && bitArray[len(bitArray)-1]>>tail != 0 {
+		return fmt.Errorf("%w: bits set past the end", ErrCorruptFilter)
+	}
+	bf.bitArray = bitArray
+	bf.size = uint(size)
+	bf.hashCount = uint(hashCount)
+	bf.seed = binary.BigEndian.Uint64(data[24:])
+	bf.elementCount.Store(binary.BigEndian.Uint64(data[16:]))
+	return nil
+}
@@ -0,0 +1,77 @@
+package DataStructures
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"sync/atomic"
+)
+
+var ErrIncompatibleFilters = errors.New("bloom: filters differ in size, hash count or seed")
+
+// compatible checks that 'other' puts every item on the same bits as 'bf'
+func (bf *BloomFilter) compatible(other *BloomFilter) error {
+	if bf.size != other.size || bf.hashCount != other.hashCount || bf.seed != other.seed {
+		return fmt.Errorf("%w: %d bits, %d hashes, seed %d against %d bits, %d hashes, seed %d",
+			ErrIncompatibleFilters, bf.size, bf.hashCount, bf.seed, other.size, other.hashCount, other.seed)
+	}
+	return nil
+}
+
+// Union adds every item of 'other' to 'bf', which then holds what either held.
+// It is exactly the filter the items of both would have built, and its
+// ElementCount counts the items added to either. Items may be added to either
+// filter while it runs
+func (bf *BloomFilter) Union(other *BloomFilter) error {
+	if err := bf.compatible(other); err != nil {
+		return err
+	}
+	for i := range bf.bitArray {
+		mask := atomic.LoadUint64(&other.bitArray[i])
+		updateWord(&bf.bitArray[i], func(w uint64) uint64 { return w | mask })
+	}
+	bf.elementCount.Add(other.elementCount.Load())
+	return nil
+}
+
+// Intersect keeps in 'bf' only the bits 'other' also has, so it holds at least
+// the items both held. The result can test present for more items than a
+// filter built from the common items alone, as a bit set by different items
+// in each filter survives. How many items are left is not known, ElementCount
+// stays as it was and EstimatedCardinality gives an estimate.
+// Like UnmarshalBinary it needs 'bf' to itself: a bit an Add sets while it
+// runs may be cleared again. Items may still be added to 'other'
+func (bf *BloomFilter) Intersect(other *BloomFilter) error {
+	if err := bf.compatible(other); err != nil {
+		return err
+	}
+	for i := range bf.bitArray {
+		mask := atomic.LoadUint64(&other.bitArray[i])
+		updateWord(&bf.bitArray[i], func(w uint64) uint64 { return w & mask })
+	}
+	return nil
+}
+
+// updateWord atomically replaces the word at 'addr' with 'change' of it
+func updateWord(addr *uint64, change func(uint64) uint64) {
+	for {
+		old := atomic.LoadUint64(addr)
+		if atomic.CompareAndSwapUint64(addr, old, change(old)) {
+			return
+		}
+	}
+}
+
+// EstimatedCardinality estimates how many distinct items were added from the
+// fraction of bits set, n = -(m/k) ln(1 - X/m). Unlike ElementCount it ignores
+// repeated items and stays meaningful after Union and Intersect. A filter with
+// every bit set could hold any number of items and gives +Inf
+func (bf *BloomFilter) EstimatedCardinality() float64 {
+	filledBits := 0
+	for i := range bf.bitArray {
+		filledBits += bits.OnesCount64(atomic.LoadUint64(&bf.bitArray[i]))
+	}
+	m, k := float64(bf.size), float64(bf.hashCount)
+	return -m / k * math.Log(1-float64(filledBits)/m)
+}
@@ -0,0 +1,153 @@
+package DataStructures
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+)
+
+func TestBloomFilterUnion(t *testing.T) {
+	a, b := NewBloomFilter(2000, 0.01), NewBloomFilter(2000, 0.01)
+	whole := NewBloomFilter(2000, 0.01)
+	for i := 0; i < 1000; i++ {
+		item := []byte(fmt.Sprintf("seg-%d", i))
+		if i%2 == 0 {
+			a.Add(item)
+		} else {
+			b.Add(item)
+		}
+		whole.Add(item)
+	}
+	if err := a.Union(b); err != nil {
+		t.Fatal(err)
+	}
+	// The union is bit for bit the filter built from every item
+	for i := range whole.bitArray {
+		if a.bitArray[i] != whole.bitArray[i] {
+			t.Fatalf("word %d is %x, the filter of every item has %x", i, a.bitArray[i], whole.bitArray[i])
+		}
+	}
+	if a.ElementCount() != 1000 {
+		t.Errorf("ElementCount after Union = %d, want 1000", a.ElementCount())
+	}
+	if n := a.EstimatedCardinality(); n < 950 || n > 1050 {
+		t.Errorf("EstimatedCardinality after Union = %v, want about 1000", n)
+	}
+	// 'b' is untouched
+	if b.ElementCount() != 500 {
+		t.Error("Union changed its argument")
+	}
+}
+
+func TestBloomFilterIntersect(t *testing.T) {
+	a, b := NewBloomFilter(1000, 0.01), NewBloomFilter(1000, 0.01)
+	for i := 0; i < 600; i++ {
+		a.Add([]byte(fmt.Sprintf("k%d", i)))
+	}
+	for i := 400; i < 1000; i++ {
+		b.Add([]byte(fmt.Sprintf("k%d", i)))
+	}
+	if err := a.Intersect(b); err != nil {
+		t.Fatal(err)
+	}
+	// Items in both are kept
+	for i := 400; i < 600; i++ {
+		if !a.Contains([]byte(fmt.Sprintf("k%d", i))) {
+			t.Fatalf("k%d is in both filters but not the intersection", i)
+		}
+	}
+	// Most items in only one are gone
+	present := 0
+	for i := 0; i < 400; i++ {
+		if a.Contains([]byte(fmt.Sprintf("k%d", i))) {
+			present++
+		}
+	}
+	if present > 40 {
+		t.Errorf("%d of 400 items only in the first filter survived", present)
+	}
+	// Only the estimate follows the intersection, the count of added items stays
+	if n := a.EstimatedCardinality(); n < 200 || n > 300 {
+		t.Errorf("EstimatedCardinality after Intersect = %v, want a little over 200", n)
+	}
+	if a.ElementCount() != 600 {
+		t.Errorf("ElementCount after Intersect = %d, want the 600 added", a.ElementCount())
+	}
+}
+
+func TestBloomFilterIncompatible(t *testing.T) {
+	base := NewBloomFilterWithSeed(100, 0.01, 1)
+	for name, other := range map[string]*BloomFilter{
+		"size":   NewBloomFilterWithSeed(200, 0.01, 1),
+		"hashes": {bitArray: make([]uint64, len(base.bitArray)), size: base.size, hashCount: base.hashCount + 1, seed: 1},
+		"seed":   NewBloomFilterWithSeed(100, 0.01, 2),
+	} {
+		if err := base.Union(other); !errors.Is(err, ErrIncompatibleFilters) {
+			t.Errorf("Union with a different %s: got %v", name, err)
+		}
+		if err := base.Intersect(other); !errors.Is(err, ErrIncompatibleFilters) {
+			t.Errorf("Intersect with a different %s: got %v", name, err)
+		}
+	}
+}
+
+func TestBloomFilterEstimatedCardinality(t *testing.T) {
+	bf := NewBloomFilter(10000, 0.01)
+	if bf.EstimatedCardinality() != 0 {
+		t.Fatalf("empty filter estimates %v items", bf.EstimatedCardinality())
+	}
+	for _, n := range []int{10, 100, 1000, 10000, 20000} {
+		for i := 0; i < n; i++ {
+			// Repeats do not count
+			bf.Add([]byte(fmt.Sprintf("item-%d", i)))
+		}
+		if got := bf.EstimatedCardinality(); math.Abs(got-float64(n)) > 0.05*float64(n)+2 {
+			t.Errorf("%d distinct items estimated as %v", n, got)
+		}
+	}
+
+	full := NewBloomFilter(10, 0.1)
+	for i := range full.bitArray {
+		full.bitArray[i] = math.MaxUint64
+	}
+	full.bitArray[len(full.bitArray)-1] >>= 64 - full.size%64
+	if !math.IsInf(full.EstimatedCardinality(), 1) {
+		t.Errorf("saturated filter estimates %v items", full.EstimatedCardinality())
+	}
+}
+
+// Workers fill their own filters and merge them into a shared one while it is read
+func TestBloomFilterConcurrentUnion(t *testing.T) {
+	const workers = 4
+	table := NewBloomFilter(4000, 0.01)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			segment := NewBloomFilter(4000, 0.01)
+			for i := 0; i < 1000; i++ {
+				segment.Add([]byte(fmt.Sprintf("w%d-%d", w, i)))
+			}
+			if err := table.Union(segment); err != nil {
+				t.Error(err)
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				table.Contains([]byte(fmt.Sprintf("w0-%d", i)))
+			}
+		}()
+	}
+	wg.Wait()
+	for w := 0; w < workers; w++ {
+		for i := 0; i < 1000; i++ {
+			if !table.Contains([]byte(fmt.Sprintf("w%d-%d", w, i))) {
+				t.Fatalf("w%d-%d lost in a concurrent Union", w, i)
+			}
+		}
+	}
+}
@@ -2,10 +2,23 @@ package DataStructures
 
 import (
 	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"math"
+	"sync"
+	"sync/atomic"
 	"testing"
 )
 
+// Compile time check that a BloomFilter can be saved with the standard interfaces
+var (
+	_ encoding.BinaryMarshaler   = (*BloomFilter)(nil)
+	_ encoding.BinaryUnmarshaler = (*BloomFilter)(nil)
+)
+
 func TestBloomFilter(t *testing.T) {
 	t.Run("Test_NewBloomFilter_BasicParameters", func(t *testing.T) {
 		expectedElements := uint(100)
@@ -133,7 +146,7 @@ func TestBloomFilter(t *testing.T) {
 		bf.Add(item)
 
 		for _, pos := range hashPositions {
-			if !bf.bitArray[pos] {
+			if !bf.hasBit(pos) {
 				t.Errorf("bitArray at position %d should be true after adding the item", pos)
 			}
 		}
@@ -172,3 +185,187 @@ func TestBloomFilter(t *testing.T) {
 		}
 	})
 }
+
+func TestBloomFilterBitset(t *testing.T) {
+	bf := NewBloomFilter(1000, 0.01)
+	if want := (bf.Size() + 63) / 64; uint(len(bf.bitArray)) != want {
+		t.Fatalf("%d bits are held in %d words, want %d", bf.Size(), len(bf.bitArray), want)
+	}
+	set := map[uint]bool{}
+	for i := 0; i < 1000; i++ {
+		item := []byte(fmt.Sprintf("item-%d", i))
+		for _, pos := range bf.getHashValues(item) {
+			set[pos] = true
+		}
+		bf.Add(item)
+	}
+	for pos := uint(0); pos < bf.Size(); pos++ {
+		if bf.hasBit(pos) != set[pos] {
+			t.Fatalf("bit %d is %v, want %v", pos, bf.hasBit(pos), set[pos])
+		}
+	}
+
+	k, fill := float64(bf.HashCount()), float64(len(set))/float64(bf.Size())
+	want := math.Pow(fill, k)
+	if got := bf.CurrentFalsePositiveRate(); math.Abs(got-want) > 1e-12 {
+		t.Fatalf("CurrentFalsePositiveRate = %v, want %v", got, want)
+	}
+}
+
+func TestBloomFilterMarshalBinary(t *testing.T) {
+	bf := NewBloomFilterWithSeed(500, 0.02, 42)
+	for i := 0; i < 300; i++ {
+		bf.Add([]byte(fmt.Sprintf("key-%d", i)))
+	}
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var loaded BloomFilter
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Size() != bf.Size() || loaded.HashCount() != bf.HashCount() ||
+		loaded.ElementCount() != 300 || loaded.Seed() != 42 {
+		t.Fatalf("loaded size %d hashes %d elements %d seed %d", loaded.Size(), loaded.HashCount(), loaded.ElementCount(), loaded.Seed())
+	}
+	for i := 0; i < 300; i++ {
+		if !loaded.Contains([]byte(fmt.Sprintf("key-%d", i))) {
+			t.Fatalf("loaded filter lost key-%d", i)
+		}
+	}
+	if loaded.CurrentFalsePositiveRate() != bf.CurrentFalsePositiveRate() {
+		t.Fatal("loaded filter has a different fill")
+	}
+	// The loaded filter keeps working
+	loaded.Add([]byte("new"))
+	if !loaded.Contains([]byte("new")) || loaded.ElementCount() != 301 {
+		t.Fatal("loaded filter does not take new items")
+	}
+
+	// A different seed puts items on different bits
+	other := NewBloomFilterWithSeed(500, 0.02, 43)
+	a, b := bf.getHashValues([]byte("key-1")), other.getHashValues([]byte("key-1"))
+	if fmt.Sprint(a) == fmt.Sprint(b) {
+		t.Error("seed does not change the hash positions")
+	}
+}
+
+func TestBloomFilterUnmarshalCorrupt(t *testing.T) {
+	bf := NewBloomFilter(100, 0.01)
+	bf.Add([]byte("x"))
+	data, _ := bf.MarshalBinary()
+
+	for name, corrupt := range map[string][]byte{
+		"empty":       nil,
+		"short":       data[:10],
+		"bad magic":   append([]byte("XXXX"), data[4:]...),
+		"truncated":   data[:len(data)-1],
+		"extra":       append(bytes.Clone(data), 0),
+		"zero size":   append(append(bytes.Clone(data[:4]), make([]byte, 8)...), data[12:]...),
+		"huge size":   append(append(bytes.Clone(data[:4]), 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF), data[12:]...),
+		"no hashes":   append(append(bytes.Clone(data[:12]), 0, 0, 0, 0), data[16:]...),
+		"many hashes": append(append(bytes.Clone(data[:12]), 0xFF, 0xFF, 0xFF, 0xFF), data[16:]...),
+		"65 hashes":   append(append(bytes.Clone(data[:12]), 0, 0, 0, 65), data[16:]...),
+		"tail bits":   append(bytes.Clone(data[:len(data)-8]), 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF),
+	} {
+		var loaded BloomFilter
+		if err := loaded.UnmarshalBinary(corrupt); !errors.Is(err, ErrCorruptFilter) {
+			t.Errorf("%s: expected ErrCorruptFilter, got %v", name, err)
+		}
+	}
+}
+
+func TestBloomFilterEstimateMatchesMeasured(t *testing.T) {
+	bf := NewBloomFilter(10000, 0.01)
+	for i := 0; i < 10000; i++ {
+		bf.Add([]byte(fmt.Sprintf("in-%d", i)))
+	}
+	const probes = 100000
+	positives := 0
+	for i := 0; i < probes; i++ {
+		if bf.Contains([]byte(fmt.Sprintf("out-%d", i))) {
+			positives++
+		}
+	}
+	measured, estimate := float64(positives)/probes, bf.CurrentFalsePositiveRate()
+	if estimate < measured/2 || estimate > measured*2 {
+		t.Fatalf("estimated false positive rate %v, measured %v", estimate, measured)
+	}
+}
+
+func TestBloomFilterHashCountLimit(t *testing.T) {
+	// A tiny target rate asks for more hashes than are ever useful
+	bf := NewBloomFilter(10, 1e-30)
+	if bf.HashCount() != maxHashCount {
+		t.Fatalf("HashCount = %d, want it capped at %d", bf.HashCount(), maxHashCount)
+	}
+	data, _ := bf.MarshalBinary()
+	var loaded BloomFilter
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("a filter at the limit should load: %v", err)
+	}
+}
+
+func TestSeededFNV(t *testing.T) {
+	// Positions must not move, filters saved before hashing went stateless still load
+	for _, seed := range []uint64{0, 1, 42, math.MaxUint64} {
+		for _, item := range []string{"", "a", "hello world", "\x00\xff"} {
+			h := fnv.New64()
+			binary.Write(h, binary.LittleEndian, seed)
+			h.Write([]byte(item))
+			if got, want := seededFNV(seed, []byte(item)), h.Sum64(); got != want {
+				t.Errorf("seededFNV(%d, %q) = %x, hash/fnv gives %x", seed, item, got, want)
+			}
+		}
+	}
+}
+
+func TestBloomFilterConcurrent(t *testing.T) {
+	const writers, perWriter = 4, 2000
+	bf := NewBloomFilter(writers*perWriter, 0.01)
+	key := func(w, i int) []byte { return []byte(fmt.Sprintf("w%d-%d", w, i)) }
+
+	// Items added before the race must test present throughout it
+	for i := 0; i < perWriter; i++ {
+		bf.Add(key(-1, i))
+	}
+
+	var wg sync.WaitGroup
+	var missing atomic.Int64
+	for w := 0; w < writers; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				bf.Add(key(w, i))
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				if !bf.Contains(key(-1, i)) {
+					missing.Add(1)
+				}
+				bf.CurrentFalsePositiveRate()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if missing.Load() != 0 {
+		t.Fatalf("%d lookups of present items failed during inserts", missing.Load())
+	}
+	if bf.ElementCount() != (writers+1)*perWriter {
+		t.Fatalf("ElementCount = %d, want %d", bf.ElementCount(), (writers+1)*perWriter)
+	}
+	// Every bit set by a concurrent Add survived the others
+	for w := 0; w < writers; w++ {
+		for i := 0; i < perWriter; i++ {
+			if !bf.Contains(key(w, i)) {
+				t.Fatalf("%s lost to a concurrent Add", key(w, i))
+			}
+		}
+	}
+}
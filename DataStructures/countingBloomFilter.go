@@ -0,0 +1,129 @@
+package DataStructures
+
+import "math"
+
+// counterMax is the largest value a counter holds. Counters are 4 bits, with
+// the usual sizing one only overflows with odds around 1.37e-15 per counter
+const counterMax = 15
+
+// CountingBloomFilter is a Bloom filter whose bits are small counters, so items
+// can be removed again. Add bumps the item's counters and Remove lowers them.
+//
+// A counter that reaches counterMax saturates and stays there for good, since
+// it no longer knows how many items share it. A saturated counter can only
+// cause false positives, never false negatives.
+//
+// Unlike BloomFilter it is not safe for concurrent use
+type CountingBloomFilter struct {
+	counters     []byte // two 4 bit counters per byte, counter i in the low nibble when i is even
+	size         uint   // number of counters
+	hashCount    uint
+	seed         uint64
+	elementCount uint
+}
+
+// NewCountingBloomFilter creates a counting Bloom filter sized like NewBloomFilter
+func NewCountingBloomFilter(expectedElements uint, falsePositiveRate float64) *CountingBloomFilter {
+	return NewCountingBloomFilterWithSeed(expectedElements, falsePositiveRate, 0)
+}
+
+// NewCountingBloomFilterWithSeed is NewCountingBloomFilter with its hashes seeded by 'seed'
+func NewCountingBloomFilterWithSeed(expectedElements uint, falsePositiveRate float64, seed uint64) *CountingBloomFilter {
+	size := calculateSize(expectedElements, falsePositiveRate)
+	return &CountingBloomFilter{
+		counters:  make([]byte, (size+1)/2),
+		size:      size,
+		hashCount: calculateHashCount(size, expectedElements),
+		seed:      seed,
+	}
+}
+
+func (cf *CountingBloomFilter) getHashValues(item []byte) []uint {
+	return hashPositions(cf.seed, item, cf.hashCount, cf.size)
+}
+
+// counter reads counter 'pos'
+func (cf *CountingBloomFilter) counter(pos uint) byte {
+	return cf.counters[pos/2] >> (4 * (pos % 2)) & 0x0F
+}
+
+// setCounter stores 'v', which fits in 4 bits, as counter 'pos'
+func (cf *CountingBloomFilter) setCounter(pos uint, v byte) {
+	shift := 4 * (pos % 2)
+	cf.counters[pos/2] = cf.counters[pos/2]&^(0x0F<<shift) | v<<shift
+}
+
+// Add adds an item to the filter
+func (cf *CountingBloomFilter) Add(item []byte) {
+	for _, pos := range cf.getHashValues(item) {
+		if c := cf.counter(pos); c < counterMax {
+			cf.setCounter(pos, c+1)
+		}
+	}
+	cf.elementCount++
+}
+
+// Contains checks if an item might be in the set
+func (cf *CountingBloomFilter) Contains(item []byte) bool {
+	for _, pos := range cf.getHashValues(item) {
+		if cf.counter(pos) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Remove takes back one Add of 'item'. Items the filter surely does not hold
+// are ignored, removing an item that was never added but tests as present
+// lowers counters other items rely on and can make them test absent
+func (cf *CountingBloomFilter) Remove(item []byte) {
+	if !cf.Contains(item) {
+		return
+	}
+	for _, pos := range cf.getHashValues(item) {
+		if c := cf.counter(pos); c < counterMax {
+			cf.setCounter(pos, c-1)
+		}
+	}
+	if cf.elementCount > 0 {
+		cf.elementCount--
+	}
+}
+
+// CurrentFalsePositiveRate estimates the false positive rate from how many counters are nonzero
+func (cf *CountingBloomFilter) CurrentFalsePositiveRate() float64 {
+	filled := 0
+	for pos := uint(0); pos < cf.size; pos++ {
+		if cf.counter(pos) != 0 {
+			filled++
+		}
+	}
+	fillRatio := float64(filled) / float64(cf.size)
+	return math.Pow(fillRatio, float64(cf.hashCount))
+}
+
+// Saturated is the number of counters stuck at their maximum
+func (cf *CountingBloomFilter) Saturated() uint {
+	n := uint(0)
+	for pos := uint(0); pos < cf.size; pos++ {
+		if cf.counter(pos) == counterMax {
+			n++
+		}
+	}
+	return n
+}
+
+// ElementCount returns the number of items added and not removed
+func (cf *CountingBloomFilter) ElementCount() uint {
+	return cf.elementCount
+}
+
+// Size returns the number of counters
+func (cf *CountingBloomFilter) Size() uint {
+	return cf.size
+}
+
+// HashCount returns the number of hash functions used
+func (cf *CountingBloomFilter) HashCount() uint {
+	return cf.hashCount
+}
@@ -0,0 +1,111 @@
+package DataStructures
+
+import (
+	"fmt"
+	"testing"
+)
+
+var _ Exister = (*CountingBloomFilter)(nil)
+
+func TestCountingBloomFilter(t *testing.T) {
+	t.Run("Add_Remove", func(t *testing.T) {
+		cf := NewCountingBloomFilter(1000, 0.01)
+		for i := 0; i < 1000; i++ {
+			cf.Add([]byte(fmt.Sprintf("row-%d", i)))
+		}
+		for i := 0; i < 1000; i += 2 {
+			cf.Remove([]byte(fmt.Sprintf("row-%d", i)))
+		}
+		if cf.ElementCount() != 500 {
+			t.Fatalf("ElementCount = %d, want 500", cf.ElementCount())
+		}
+		// No false negatives for what is left
+		for i := 1; i < 1000; i += 2 {
+			if !cf.Contains([]byte(fmt.Sprintf("row-%d", i))) {
+				t.Fatalf("row-%d went missing after removing its neighbours", i)
+			}
+		}
+		// Removed keys are mostly reported absent again
+		present := 0
+		for i := 0; i < 1000; i += 2 {
+			if cf.Contains([]byte(fmt.Sprintf("row-%d", i))) {
+				present++
+			}
+		}
+		if present > 25 {
+			t.Errorf("%d of 500 removed keys still test present", present)
+		}
+	})
+
+	t.Run("Remove_Everything", func(t *testing.T) {
+		cf := NewCountingBloomFilter(100, 0.01)
+		for i := 0; i < 100; i++ {
+			cf.Add([]byte(fmt.Sprintf("k%d", i)))
+		}
+		for i := 0; i < 100; i++ {
+			cf.Remove([]byte(fmt.Sprintf("k%d", i)))
+		}
+		for pos := uint(0); pos < cf.Size(); pos++ {
+			if c := cf.counter(pos); c != 0 {
+				t.Fatalf("counter %d is %d after removing every item", pos, c)
+			}
+		}
+		if cf.CurrentFalsePositiveRate() != 0 || cf.ElementCount() != 0 {
+			t.Fatal("filter is not empty again")
+		}
+	})
+
+	t.Run("Remove_Absent", func(t *testing.T) {
+		cf := NewCountingBloomFilter(100, 0.01)
+		cf.Add([]byte("kept"))
+		cf.Remove([]byte("never-added"))
+		if !cf.Contains([]byte("kept")) || cf.ElementCount() != 1 {
+			t.Fatal("removing an absent item changed the filter")
+		}
+	})
+
+	t.Run("Duplicates", func(t *testing.T) {
+		cf := NewCountingBloomFilter(100, 0.01)
+		cf.Add([]byte("dup"))
+		cf.Add([]byte("dup"))
+		cf.Remove([]byte("dup"))
+		if !cf.Contains([]byte("dup")) {
+			t.Fatal("one Remove undid both Adds")
+		}
+		cf.Remove([]byte("dup"))
+		if cf.Contains([]byte("dup")) {
+			t.Fatal("item still present after removing both Adds")
+		}
+	})
+
+	t.Run("Saturation", func(t *testing.T) {
+		cf := NewCountingBloomFilter(100, 0.01)
+		item := []byte("hot")
+		for i := 0; i < 40; i++ {
+			cf.Add(item)
+		}
+		if cf.Saturated() == 0 {
+			t.Fatal("40 Adds of one item saturated no counter")
+		}
+		// Saturated counters stick, so the item survives more Removes than it had Adds
+		for i := 0; i < 50; i++ {
+			cf.Remove(item)
+		}
+		if !cf.Contains(item) {
+			t.Fatal("saturated counters were decremented")
+		}
+	})
+
+	t.Run("Nibbles", func(t *testing.T) {
+		cf := NewCountingBloomFilter(10, 0.1)
+		cf.setCounter(4, 7)
+		cf.setCounter(5, counterMax)
+		if cf.counter(4) != 7 || cf.counter(5) != counterMax || cf.counter(3) != 0 || cf.counter(6) != 0 {
+			t.Fatalf("neighbouring counters interfere: %v", cf.counters[:4])
+		}
+		cf.setCounter(5, 0)
+		if cf.counter(4) != 7 || cf.counter(5) != 0 {
+			t.Fatalf("clearing counter 5 touched counter 4: %v", cf.counters[:4])
+		}
+	})
+}
@@ -0,0 +1,288 @@
+package DataStructures
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"math/rand"
+)
+
+const (
+	cuckooBucketSize = 4
+	// cuckooMaxKicks bounds how many fingerprints one insert may relocate
+	cuckooMaxKicks = 500
+	// cuckooMaxLoad is the load factor buckets of four reliably reach, filters
+	// are sized so the expected element count stays under it
+	cuckooMaxLoad = 0.95
+)
+
+var ErrFilterFull = errors.New("cuckoo: filter is full")
+
+// CuckooFilter answers the same question as a Bloom filter but stores a small
+// fingerprint of every item, so items can be deleted again. Each item has two
+// candidate buckets of cuckooBucketSize slots, the second derived from the
+// first and the fingerprint alone, which lets an insert move fingerprints
+// between their buckets to make room. Fingerprints are packed at their exact
+// bit width, below a false positive rate of about 0.3% they take less space
+// than a Bloom filter for the same rate.
+//
+// Like the counting filter it is not safe for concurrent use
+type CuckooFilter struct {
+	table       []uint64 // fingerprints of 'fpBits' bits packed back to back, 0 is an empty slot
+	fpBits      uint
+	bucketMask  uint64 // the bucket count is a power of two
+	count       uint   // fingerprints in the table
+	victim      uint64 // fingerprint the last failed insert could not place, 0 when none
+	victimIndex uint64
+	overflow    map[cuckooEntry]uint // fingerprints Add could not place in the table, see Add
+	overflowed  uint                 // entries in 'overflow'
+	seed        uint64
+	rng         *rand.Rand
+}
+
+// cuckooEntry names a fingerprint outside the table by the lower of its two buckets
+type cuckooEntry struct {
+	fp, index uint64
+}
+
+// NewCuckooFilter creates a cuckoo filter for 'expectedElements' items at
+// 'falsePositiveRate', it takes the same arguments as NewBloomFilter
+func NewCuckooFilter(expectedElements uint, falsePositiveRate float64) *CuckooFilter {
+	return NewCuckooFilterWithSeed(expectedElements, falsePositiveRate, 0)
+}
+
+// NewCuckooFilterWithSeed is NewCuckooFilter with its hashes seeded by 'seed'
+func NewCuckooFilterWithSeed(expectedElements uint, falsePositiveRate float64, seed uint64) *CuckooFilter {
+	// A lookup compares against 2*cuckooBucketSize fingerprints, each matching
+	// with odds 1/2^fpBits
+	fpBits := uint(math.Ceil(math.Log2(2 * cuckooBucketSize / falsePositiveRate)))
+	fpBits = min(max(fpBits, 4), 32)
+
+	buckets := uint64(math.Ceil(float64(expectedElements) / (cuckooBucketSize * cuckooMaxLoad)))
+	buckets = max(buckets, 1)
+	if bits.OnesCount64(buckets) != 1 {
+		buckets = 1 << bits.Len64(buckets)
+	}
+	slots := buckets * cuckooBucketSize
+	return &CuckooFilter{
+		table:      make([]uint64, (slots*uint64(fpBits)+63)/64),
+		fpBits:     fpBits,
+		bucketMask: buckets - 1,
+		seed:       seed,
+		rng:        rand.New(rand.NewSource(int64(seed))),
+	}
+}
+
+// mix64 is the splitmix64 finalizer, it spreads every input bit over the output
+func mix64(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	return h ^ h>>31
+}
+
+// locate gives the fingerprint of 'item' and its first bucket
+func (cf *CuckooFilter) locate(item []byte) (fp, index uint64) {
+	h := mix64(seededFNV(cf.seed, item))
+	fp = (h>>32)%(1<<cf.fpBits-1) + 1
+	return fp, h & cf.bucketMask
+}
+
+// altIndex is the other bucket of fingerprint 'fp' in bucket 'index'. It is
+// its own inverse, so either bucket leads to the other
+func (cf *CuckooFilter) altIndex(index, fp uint64) uint64 {
+	return (index ^ mix64(fp)) & cf.bucketMask
+}
+
+// slot reads slot 'j' of bucket 'index'
+func (cf *CuckooFilter) slot(index uint64, j int) uint64 {
+	off := (index*cuckooBucketSize + uint64(j)) * uint64(cf.fpBits)
+	word, shift := off/64, off%64
+	v := cf.table[word] >> shift
+	if shift+uint64(cf.fpBits) > 64 {
+		v |= cf.table[word+1] << (64 - shift)
+	}
+	return v & (1<<cf.fpBits - 1)
+}
+
+// setSlot stores 'fp' in slot 'j' of bucket 'index'
+func (cf *CuckooFilter) setSlot(index uint64, j int, fp uint64) {
+	off := (index*cuckooBucketSize + uint64(j)) * uint64(cf.fpBits)
+	word, shift := off/64, off%64
+	mask := uint64(1)<<cf.fpBits - 1
+	cf.table[word] = cf.table[word]&^(mask<<shift) | fp<<shift
+	if shift+uint64(cf.fpBits) > 64 {
+		rest := 64 - shift
+		cf.table[word+1] = cf.table[word+1]&^(mask>>rest) | fp>>rest
+	}
+}
+
+// place puts 'fp' in a free slot of bucket 'index' if there is one
+func (cf *CuckooFilter) place(index, fp uint64) bool {
+	for j := 0; j < cuckooBucketSize; j++ {
+		if cf.slot(index, j) == 0 {
+			cf.setSlot(index, j, fp)
+			cf.count++
+			return true
+		}
+	}
+	return false
+}
+
+// entry is the cuckooEntry of 'fp' in bucket 'index', the same from either bucket
+func (cf *CuckooFilter) entry(index, fp uint64) cuckooEntry {
+	return cuckooEntry{fp: fp, index: min(index, cf.altIndex(index, fp))}
+}
+
+// has reports whether bucket 'index' holds 'fp'
+func (cf *CuckooFilter) has(index, fp uint64) bool {
+	for j := 0; j < cuckooBucketSize; j++ {
+		if cf.slot(index, j) == fp {
+			return true
+		}
+	}
+	return false
+}
+
+// Insert adds an item, or fails with ErrFilterFull and leaves the filter as it
+// was. The insert that fills the filter still succeeds, its last displaced
+// fingerprint is held aside and the inserts after it fail. An item added more
+// than 2*cuckooBucketSize times also fills the filter, its two buckets then
+// hold nothing else
+func (cf *CuckooFilter) Insert(item []byte) error {
+	if cf.victim != 0 {
+		return ErrFilterFull
+	}
+	fp, index := cf.locate(item)
+	cf.settle(fp, index)
+	return nil
+}
+
+// settle stores 'fp', which belongs in bucket 'index' or its alternate. When
+// both are full it evicts a random fingerprint to its other bucket and repeats
+// with whatever that one displaces. If that runs past cuckooMaxKicks the last
+// displaced fingerprint becomes the victim, so none is ever lost
+func (cf *CuckooFilter) settle(fp, index uint64) {
+	if cf.place(index, fp) {
+		return
+	}
+	index = cf.altIndex(index, fp)
+	if cf.place(index, fp) {
+		return
+	}
+	for kick := 0; kick < cuckooMaxKicks; kick++ {
+		j := cf.rng.Intn(cuckooBucketSize)
+		evicted := cf.slot(index, j)
+		cf.setSlot(index, j, fp)
+		fp, index = evicted, cf.altIndex(index, evicted)
+		if cf.place(index, fp) {
+			return
+		}
+	}
+	cf.victim, cf.victimIndex = fp, index
+}
+
+// Add adds an item, implementing Exister. When the filter is full the
+// fingerprint is kept outside the table, where lookups still find it, and
+// moves into the table once Deletes make room. Past capacity the filter grows
+// and slows down instead of forgetting items, use Insert to see the error
+func (cf *CuckooFilter) Add(item []byte) {
+	if err := cf.Insert(item); err != nil {
+		fp, index := cf.locate(item)
+		if cf.overflow == nil {
+			cf.overflow = make(map[cuckooEntry]uint)
+		}
+		cf.overflow[cf.entry(index, fp)]++
+		cf.overflowed++
+	}
+}
+
+// Contains checks if an item might be in the set
+func (cf *CuckooFilter) Contains(item []byte) bool {
+	fp, i1 := cf.locate(item)
+	i2 := cf.altIndex(i1, fp)
+	if cf.victim == fp && (cf.victimIndex == i1 || cf.victimIndex == i2) {
+		return true
+	}
+	return cf.has(i1, fp) || cf.has(i2, fp) || cf.overflow[cf.entry(i1, fp)] > 0
+}
+
+// Delete removes one copy of an item and reports whether it found one. Only
+// delete items that were added, deleting an item that merely tests present
+// removes the fingerprint of another item
+func (cf *CuckooFilter) Delete(item []byte) bool {
+	fp, i1 := cf.locate(item)
+	i2 := cf.altIndex(i1, fp)
+	if cf.victim == fp && (cf.victimIndex == i1 || cf.victimIndex == i2) {
+		cf.victim = 0
+		cf.resettle()
+		return true
+	}
+	for _, index := range [2]uint64{i1, i2} {
+		for j := 0; j < cuckooBucketSize; j++ {
+			if cf.slot(index, j) == fp {
+				cf.setSlot(index, j, 0)
+				cf.count--
+				cf.resettle()
+				return true
+			}
+		}
+	}
+	if e := cf.entry(i1, fp); cf.overflow[e] > 0 {
+		cf.unstash(e)
+		return true
+	}
+	return false
+}
+
+// resettle moves the victim back into the table now there may be room, then
+// the fingerprints Add could not place until one becomes the victim again
+func (cf *CuckooFilter) resettle() {
+	if cf.victim != 0 {
+		fp, index := cf.victim, cf.victimIndex
+		cf.victim = 0
+		cf.settle(fp, index)
+	}
+	for e := range cf.overflow {
+		if cf.victim != 0 {
+			return
+		}
+		cf.unstash(e)
+		cf.settle(e.fp, e.index)
+	}
+}
+
+// unstash drops one copy of 'e' from the overflow
+func (cf *CuckooFilter) unstash(e cuckooEntry) {
+	if cf.overflow[e]--; cf.overflow[e] == 0 {
+		delete(cf.overflow, e)
+	}
+	cf.overflowed--
+}
+
+// Count is the number of fingerprints held, including one set aside by a
+// failed insert and those Add kept outside the table
+func (cf *CuckooFilter) Count() uint {
+	count := cf.count + cf.overflowed
+	if cf.victim != 0 {
+		count++
+	}
+	return count
+}
+
+// Capacity is the number of fingerprint slots
+func (cf *CuckooFilter) Capacity() uint {
+	return uint(cf.bucketMask+1) * cuckooBucketSize
+}
+
+// LoadFactor is the fraction of slots in use. Inserts start failing somewhere
+// past cuckooMaxLoad
+func (cf *CuckooFilter) LoadFactor() float64 {
+	return float64(cf.count) / float64(cf.Capacity())
+}
+
+// FingerprintBits is the width of one stored fingerprint
+func (cf *CuckooFilter) FingerprintBits() uint {
+	return cf.fpBits
+}
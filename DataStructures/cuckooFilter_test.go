@@ -0,0 +1,203 @@
+package DataStructures
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+var _ Exister = (*CuckooFilter)(nil)
+
+func TestCuckooFilter(t *testing.T) {
+	t.Run("Drop_In_For_Cache", func(t *testing.T) {
+		// Same arguments as cache(size, fp), used through the interface
+		var e Exister = NewCuckooFilter(1000, 0.01)
+		for i := 0; i < 1000; i++ {
+			e.Add([]byte(fmt.Sprintf("row-%d", i)))
+		}
+		for i := 0; i < 1000; i++ {
+			if !e.Contains([]byte(fmt.Sprintf("row-%d", i))) {
+				t.Fatalf("row-%d went missing", i)
+			}
+		}
+		falsePositives := 0
+		for i := 0; i < 100000; i++ {
+			if e.Contains([]byte(fmt.Sprintf("other-%d", i))) {
+				falsePositives++
+			}
+		}
+		if rate := float64(falsePositives) / 100000; rate > 0.01 {
+			t.Errorf("false positive rate %v above 0.01", rate)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		cf := NewCuckooFilter(1000, 0.001)
+		for i := 0; i < 1000; i++ {
+			cf.Add([]byte(fmt.Sprintf("k%d", i)))
+		}
+		for i := 0; i < 1000; i += 2 {
+			if !cf.Delete([]byte(fmt.Sprintf("k%d", i))) {
+				t.Fatalf("Delete did not find k%d", i)
+			}
+		}
+		if cf.Count() != 500 {
+			t.Fatalf("Count = %d after deleting half of 1000", cf.Count())
+		}
+		for i := 0; i < 1000; i++ {
+			present := cf.Contains([]byte(fmt.Sprintf("k%d", i)))
+			if i%2 == 1 && !present {
+				t.Fatalf("k%d lost when its neighbours were deleted", i)
+			}
+			if i%2 == 0 && present {
+				// Possible only as a false positive, at 0.1% one in 500 is already unlikely
+				t.Logf("deleted k%d still tests present", i)
+			}
+		}
+		if cf.Delete([]byte("never-added")) {
+			t.Error("Delete found an item that was never added")
+		}
+	})
+
+	t.Run("Duplicates", func(t *testing.T) {
+		cf := NewCuckooFilter(100, 0.01)
+		cf.Add([]byte("dup"))
+		cf.Add([]byte("dup"))
+		cf.Delete([]byte("dup"))
+		if !cf.Contains([]byte("dup")) {
+			t.Fatal("one Delete removed both copies")
+		}
+		cf.Delete([]byte("dup"))
+		if cf.Contains([]byte("dup")) || cf.Count() != 0 {
+			t.Fatal("item still present after deleting both copies")
+		}
+	})
+
+	t.Run("Full", func(t *testing.T) {
+		cf := NewCuckooFilter(1000, 0.01)
+		capacity := cf.Capacity()
+		var err error
+		added := 0
+		for ; err == nil; added++ {
+			err = cf.Insert([]byte(fmt.Sprintf("fill-%d", added)))
+		}
+		added--
+		if !errors.Is(err, ErrFilterFull) {
+			t.Fatalf("expected ErrFilterFull, got %v", err)
+		}
+		if load := cf.LoadFactor(); load < 0.9 {
+			t.Errorf("filter of %d slots full at load %v", capacity, load)
+		}
+		// Every accepted item is still there, the last displaced one included
+		for i := 0; i < added; i++ {
+			if !cf.Contains([]byte(fmt.Sprintf("fill-%d", i))) {
+				t.Fatalf("fill-%d lost while filling the filter", i)
+			}
+		}
+		if cf.Count() != uint(added) {
+			t.Fatalf("Count = %d, %d items were accepted", cf.Count(), added)
+		}
+		// A failed Insert changes nothing, a Delete makes room again
+		if err := cf.Insert([]byte("extra")); !errors.Is(err, ErrFilterFull) {
+			t.Fatalf("second insert into a full filter: %v", err)
+		}
+		for i := 0; i < 10; i++ {
+			cf.Delete([]byte(fmt.Sprintf("fill-%d", i)))
+		}
+		if err := cf.Insert([]byte("extra")); err != nil {
+			t.Fatalf("insert after deletes: %v", err)
+		}
+		for i := 10; i < added; i++ {
+			if !cf.Contains([]byte(fmt.Sprintf("fill-%d", i))) {
+				t.Fatalf("fill-%d lost while making room", i)
+			}
+		}
+	})
+
+	t.Run("Overflowed_Add", func(t *testing.T) {
+		cf := NewCuckooFilter(8, 0.01)
+		var stored []string
+		for i := 0; ; i++ {
+			item := fmt.Sprintf("fill-%d", i)
+			if cf.Insert([]byte(item)) != nil {
+				break
+			}
+			stored = append(stored, item)
+		}
+		var lost []string
+		for i := 0; len(lost) < 8; i++ {
+			item := fmt.Sprintf("lost-%d", i)
+			if cf.Contains([]byte(item)) {
+				continue // a false positive tells us nothing
+			}
+			cf.Add([]byte(item))
+			lost = append(lost, item)
+		}
+		for _, item := range lost {
+			if !cf.Contains([]byte(item)) {
+				t.Fatalf("%s was added past capacity and tests absent", item)
+			}
+		}
+		absent := 0
+		for i := 0; i < 100; i++ {
+			if !cf.Contains([]byte(fmt.Sprintf("absent-%d", i))) {
+				absent++
+			}
+		}
+		if absent == 0 {
+			t.Fatal("an overflowed filter answers maybe to everything")
+		}
+
+		// Deletes make room and the lost fingerprints move into the table
+		for _, item := range stored {
+			if !cf.Delete([]byte(item)) {
+				t.Fatalf("Delete(%s) found nothing", item)
+			}
+		}
+		if cf.overflowed != 0 || cf.victim != 0 {
+			t.Fatalf("%d fingerprints still outside the table", cf.overflowed)
+		}
+		if cf.Count() != uint(len(lost)) {
+			t.Fatalf("Count = %d after deleting the rest, want %d", cf.Count(), len(lost))
+		}
+		for _, item := range lost {
+			if !cf.Contains([]byte(item)) {
+				t.Fatalf("%s tests absent after moving into the table", item)
+			}
+			cf.Delete([]byte(item))
+		}
+		if cf.Count() != 0 {
+			t.Fatalf("Count = %d after deleting everything", cf.Count())
+		}
+	})
+
+	t.Run("Packing", func(t *testing.T) {
+		// 13 bit fingerprints straddle word boundaries
+		cf := NewCuckooFilter(100, 2*cuckooBucketSize/float64(1<<13))
+		if cf.FingerprintBits() != 13 {
+			t.Fatalf("fingerprints are %d bits", cf.FingerprintBits())
+		}
+		buckets := cf.bucketMask + 1
+		for index := uint64(0); index < buckets; index++ {
+			for j := 0; j < cuckooBucketSize; j++ {
+				cf.setSlot(index, j, (index*cuckooBucketSize+uint64(j))%(1<<13-1)+1)
+			}
+		}
+		for index := uint64(0); index < buckets; index++ {
+			for j := 0; j < cuckooBucketSize; j++ {
+				if got, want := cf.slot(index, j), (index*cuckooBucketSize+uint64(j))%(1<<13-1)+1; got != want {
+					t.Fatalf("slot %d/%d holds %d, want %d", index, j, got, want)
+				}
+			}
+		}
+	})
+
+	t.Run("Smaller_Than_Bloom", func(t *testing.T) {
+		// Pick a count that fills the power of two table, as a Bloom filter would be sized
+		const n, rate = 3800, 0.0001
+		cf, bf := NewCuckooFilter(n, rate), NewBloomFilter(n, rate)
+		if cuckoo, bloom := 64*len(cf.table), int(bf.Size()); cuckoo >= bloom {
+			t.Errorf("cuckoo filter takes %d bits, Bloom filter %d", cuckoo, bloom)
+		}
+	})
+}
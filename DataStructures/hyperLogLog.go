@@ -0,0 +1,344 @@
+package DataStructures
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+)
+
+const (
+	MinHLLPrecision     = 4
+	MaxHLLPrecision     = 18
+	DefaultHLLPrecision = 14 // 16384 registers, a standard error of 0.81%
+
+	// Sparse sketches keep each hash at hllSparsePrecision bits of index,
+	// enough that small counts are close to exact
+	hllSparsePrecision = 25
+	hllRhoBits         = 6 // a rho fits in 6 bits, it is at most 64-4+1
+)
+
+var (
+	ErrHLLPrecision  = errors.New("hll: sketches have different precisions")
+	ErrCorruptSketch = errors.New("hll: corrupt sketch encoding")
+)
+
+// HyperLogLog estimates how many distinct items it was given in a fixed amount
+// of memory. Each item's 64 bit hash picks one of 2^precision registers by its
+// top bits, and the register keeps the longest run of leading zeros, plus
+// one, seen in the rest. Long runs are rare, so the registers together tell
+// how many distinct hashes went by. Adding an item again changes nothing.
+//
+// A new sketch is sparse: it keeps the register and run of every distinct
+// hash at a finer precision, packed in a uint32 each, which counts small sets
+// nearly exactly. Once those 4 bytes an entry would take more space than the
+// one byte registers it switches to them for good.
+//
+// It is not safe for concurrent use, not even by readers alone: Count and
+// MarshalBinary sort the sparse entries added since they last ran into place
+type HyperLogLog struct {
+	precision uint
+	registers []uint8 // 1<<precision of them, nil while sparse
+
+	// Sparse entries, index<<hllRhoBits | rho. 'sparse' is sorted with one
+	// entry per index, 'pending' holds entries added since the last flush
+	sparse  []uint32
+	pending []uint32
+}
+
+// NewHyperLogLog creates an empty sketch with 2^precision registers, a
+// standard error of 1.04/sqrt(2^precision). The precision is clamped to
+// MinHLLPrecision..MaxHLLPrecision
+func NewHyperLogLog(precision uint) *HyperLogLog {
+	return &HyperLogLog{precision: min(max(precision, MinHLLPrecision), MaxHLLPrecision)}
+}
+
+// Precision returns log2 of the number of registers
+func (h *HyperLogLog) Precision() uint {
+	return h.precision
+}
+
+// Sparse reports whether the sketch still keeps individual hashes
+func (h *HyperLogLog) Sparse() bool {
+	return h.registers == nil
+}
+
+// rho is one plus the number of leading zeros of the top 'width' bits of 'w'
+func rho(w uint64, width uint) uint8 {
+	return uint8(min(uint(bits.LeadingZeros64(w)), width) + 1)
+}
+
+// Add records an item
+func (h *HyperLogLog) Add(item []byte) {
+	hash := mix64(seededFNV(0, item))
+	if h.registers != nil {
+		h.setRegister(hash>>(64-h.precision), rho(hash<<h.precision, 64-h.precision))
+		return
+	}
+	index := uint32(hash >> (64 - hllSparsePrecision))
+	r := rho(hash<<hllSparsePrecision, 64-hllSparsePrecision)
+	h.pending = append(h.pending, index<<hllRhoBits|uint32(r))
+	// Sorting pending entries in once they are a good share of the sorted
+	// ones keeps the cost of an Add logarithmic
+	if len(h.pending) > max(64, len(h.sparse)/2) || h.sparseBytes() > 1<<h.precision {
+		h.flush()
+		h.maybeDensify()
+	}
+}
+
+func (h *HyperLogLog) setRegister(index uint64, r uint8) {
+	if r > h.registers[index] {
+		h.registers[index] = r
+	}
+}
+
+// sparseBytes is the memory the sparse entries take, the registers take a byte each
+func (h *HyperLogLog) sparseBytes() int {
+	return 4 * (len(h.sparse) + len(h.pending))
+}
+
+// flush merges the pending entries into the sorted ones, keeping the largest
+// rho of each index
+func (h *HyperLogLog) flush() {
+	if len(h.pending) == 0 {
+		return
+	}
+	// An index's entries sort by rho, the last one is the one to keep
+	slices.Sort(h.pending)
+	merged := make([]uint32, 0, len(h.sparse)+len(h.pending))
+	i, j := 0, 0
+	for i < len(h.sparse) || j < len(h.pending) {
+		var e uint32
+		if j == len(h.pending) || (i < len(h.sparse) && h.sparse[i] < h.pending[j]) {
+			e, i = h.sparse[i], i+1
+		} else {
+			e, j = h.pending[j], j+1
+		}
+		if n := len(merged); n > 0 && merged[n-1]>>hllRhoBits == e>>hllRhoBits {
+			merged[n-1] = e
+			continue
+		}
+		merged = append(merged, e)
+	}
+	h.sparse, h.pending = merged, nil
+}
+
+// maybeDensify switches to registers once the sparse entries outgrow them
+func (h *HyperLogLog) maybeDensify() {
+	if h.sparseBytes() <= 1<<h.precision {
+		return
+	}
+	h.densify()
+}
+
+// densify moves every sparse entry into registers
+func (h *HyperLogLog) densify() {
+	h.registers = make([]uint8, 1<<h.precision)
+	h.addSparse(h.sparse)
+	h.addSparse(h.pending)
+	h.sparse, h.pending = nil, nil
+}
+
+// addSparse puts sparse entries into the registers of a dense sketch
+func (h *HyperLogLog) addSparse(entries []uint32) {
+	for _, e := range entries {
+		h.setRegister(h.denseEntry(e>>hllRhoBits, uint8(e&(1<<hllRhoBits-1))))
+	}
+}
+
+// denseEntry is the register and rho the hash behind a sparse entry would
+// have given. The index bits past the dense precision lead the rest of the hash
+func (h *HyperLogLog) denseEntry(index uint32, r uint8) (uint64, uint8) {
+	extra := uint(hllSparsePrecision - h.precision)
+	low := uint64(index) & (1<<extra - 1)
+	if low != 0 {
+		return uint64(index) >> extra, rho(low<<(64-extra), extra)
+	}
+	return uint64(index) >> extra, uint8(extra) + r
+}
+
+// Count estimates the number of distinct items added. A sparse sketch sorts
+// its new entries in first, so Count changes the sketch's memory, not its contents
+func (h *HyperLogLog) Count() uint64 {
+	if h.registers == nil {
+		// The sparse entries are registers at the finer precision, almost all empty
+		h.flush()
+		q := 64 - hllSparsePrecision
+		histogram := make([]int, q+2)
+		histogram[0] = 1<<hllSparsePrecision - len(h.sparse)
+		for _, e := range h.sparse {
+			histogram[e&(1<<hllRhoBits-1)]++
+		}
+		return uint64(math.Round(hllEstimate(histogram, 1<<hllSparsePrecision)))
+	}
+
+	histogram := make([]int, 64-h.precision+2)
+	for _, r := range h.registers {
+		histogram[r]++
+	}
+	return uint64(math.Round(hllEstimate(histogram, len(h.registers))))
+}
+
+// hllEstimate is Ertl's improved estimator ("New cardinality estimation
+// algorithms for HyperLogLog sketches", 2017) over 'm' registers, where
+// histogram[k] registers hold k and the last entry is the largest value a
+// register can reach. It corrects the raw estimate's bias for small counts,
+// empty registers, and for large ones, saturated registers, so it needs
+// neither linear counting nor a table of empirical corrections
+func hllEstimate(histogram []int, m int) float64 {
+	q := len(histogram) - 2
+	fm := float64(m)
+	z := fm * hllTau(1-float64(histogram[q+1])/fm)
+	for k := q; k >= 1; k-- {
+		z = 0.5 * (z + float64(histogram[k]))
+	}
+	z += fm * hllSigma(float64(histogram[0])/fm)
+	return fm * fm / (2 * math.Ln2 * z)
+}
+
+// hllSigma is the series sigma(x) = x + sum 2^(k-1) x^(2^k), which accounts
+// for empty registers
+func hllSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y, z := 1.0, x
+	for {
+		x *= x
+		next := z + x*y
+		if next == z {
+			return z
+		}
+		z, y = next, 2*y
+	}
+}
+
+// hllTau is the series tau(x) = (1 - x - sum (1 - x^(2^-k))^2 2^-k) / 3, which
+// accounts for registers at their largest value
+func hllTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		y *= 0.5
+		next := z - (1-x)*(1-x)*y
+		if next == z {
+			return z / 3
+		}
+		z = next
+	}
+}
+
+// Merge adds every item of 'other' to 'h', as if they had been added to 'h'
+// directly. Both must have the same precision
+func (h *HyperLogLog) Merge(other *HyperLogLog) error {
+	if h.precision != other.precision {
+		return fmt.Errorf("%w: %d and %d", ErrHLLPrecision, h.precision, other.precision)
+	}
+	if h.registers == nil && other.registers == nil {
+		h.pending = append(append(h.pending, other.sparse...), other.pending...)
+		h.flush()
+		h.maybeDensify()
+		return nil
+	}
+	if h.registers == nil {
+		// Force the switch, 'other' is dense
+		h.densify()
+	}
+	if other.registers == nil {
+		h.addSparse(other.sparse)
+		h.addSparse(other.pending)
+		return nil
+	}
+	for i, r := range other.registers {
+		h.setRegister(uint64(i), r)
+	}
+	return nil
+}
+
+// The binary form of a HyperLogLog, numbers big endian:
+//
+//	magic     [4]byte "HLL1"
+//	precision uint8
+//	kind      uint8   hllSparse or hllDense
+//
+// A sparse sketch continues with the number of entries as a uint32 and then
+// each entry as a uint32, index << hllRhoBits | rho, in increasing order. A
+// dense sketch continues with its 2^precision registers, a byte each
+var hllMagic = [4]byte{'H', 'L', 'L', '1'}
+
+const (
+	hllHeaderSize = 6
+	hllSparse     = 0
+	hllDense      = 1
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler. Like Count it sorts the
+// new entries of a sparse sketch in first
+func (h *HyperLogLog) MarshalBinary() ([]byte, error) {
+	out := make([]byte, hllHeaderSize)
+	copy(out, hllMagic[:])
+	out[4] = byte(h.precision)
+	if h.registers != nil {
+		out[5] = hllDense
+		return append(out, h.registers...), nil
+	}
+	out[5] = hllSparse
+	h.flush()
+	out = binary.BigEndian.AppendUint32(out, uint32(len(h.sparse)))
+	for _, e := range h.sparse {
+		out = binary.BigEndian.AppendUint32(out, e)
+	}
+	return out, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, replacing the sketch
+// with the one in 'data'
+func (h *HyperLogLog) UnmarshalBinary(data []byte) error {
+	if len(data) < hllHeaderSize || [4]byte(data[:4]) != hllMagic {
+		return fmt.Errorf("%w: bad header", ErrCorruptSketch)
+	}
+	precision := uint(data[4])
+	if precision < MinHLLPrecision || precision > MaxHLLPrecision {
+		return fmt.Errorf("%w: precision %d", ErrCorruptSketch, precision)
+	}
+	body := data[hllHeaderSize:]
+
+	switch data[5] {
+	case hllDense:
+		if len(body) != 1<<precision {
+			return fmt.Errorf("%w: %d registers for precision %d", ErrCorruptSketch, len(body), precision)
+		}
+		for i, r := range body {
+			if uint(r) > 64-precision+1 {
+				return fmt.Errorf("%w: register %d is %d", ErrCorruptSketch, i, r)
+			}
+		}
+		h.precision, h.registers, h.sparse, h.pending = precision, slices.Clone(body), nil, nil
+		return nil
+
+	case hllSparse:
+		if len(body) < 4 || uint64(len(body)-4) != 4*uint64(binary.BigEndian.Uint32(body)) {
+			return fmt.Errorf("%w: sparse entry count does not match the length", ErrCorruptSketch)
+		}
+		sparse := make([]uint32, 0, len(body)/4-1)
+		prev := int64(-1)
+		for off := 4; off < len(body); off += 4 {
+			e := binary.BigEndian.Uint32(body[off:])
+			index, r := e>>hllRhoBits, uint8(e&(1<<hllRhoBits-1))
+			if int64(index) <= prev || index >= 1<<hllSparsePrecision || r == 0 || r > 64-hllSparsePrecision+1 {
+				return fmt.Errorf("%w: sparse entry %x", ErrCorruptSketch, e)
+			}
+			prev = int64(index)
+			sparse = append(sparse, e)
+		}
+		h.precision, h.registers, h.sparse, h.pending = precision, nil, sparse, nil
+		h.maybeDensify()
+		return nil
+	}
+	return fmt.Errorf("%w: kind %d", ErrCorruptSketch, data[5])
+}
@@ -0,0 +1,205 @@
+package DataStructures
+
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*HyperLogLog)(nil)
+	_ encoding.BinaryUnmarshaler = (*HyperLogLog)(nil)
+)
+
+// hllError is how far 'got' is from the exact count 'want', relative to it
+func hllError(got uint64, want int) float64 {
+	if want == 0 {
+		return float64(got)
+	}
+	return math.Abs(float64(got)-float64(want)) / float64(want)
+}
+
+func TestHyperLogLogAccuracy(t *testing.T) {
+	for _, precision := range []uint{10, 14} {
+		h := NewHyperLogLog(precision)
+		exact := map[string]bool{}
+		// Four standard errors, a correct sketch fails this about once in 15000 checks
+		tolerance := 4 * 1.04 / math.Sqrt(float64(uint(1)<<precision))
+
+		added := 0
+		for _, n := range []int{0, 1, 10, 100, 1000, 5000, 20000, 50000, 100000, 300000} {
+			for ; added < n; added++ {
+				item := fmt.Sprintf("value-%d", added)
+				h.Add([]byte(item))
+				// Repeats must not count
+				h.Add([]byte(item))
+				exact[item] = true
+			}
+			got := h.Count()
+			if h.Sparse() {
+				// Sparse sketches are nearly exact
+				if hllError(got, len(exact)) > 0.01 && got != uint64(len(exact)) {
+					t.Errorf("precision %d, sparse: %d distinct counted as %d", precision, len(exact), got)
+				}
+				continue
+			}
+			if e := hllError(got, len(exact)); e > tolerance {
+				t.Errorf("precision %d: %d distinct counted as %d, off by %.2f%%, tolerance %.2f%%",
+					precision, len(exact), got, 100*e, 100*tolerance)
+			}
+		}
+		if h.Sparse() {
+			t.Errorf("precision %d still sparse after %d items", precision, added)
+		}
+	}
+}
+
+// TestHyperLogLogBiasRange averages many sketches between 2.5 and 5 times
+// their register count, past where linear counting helps and where the raw
+// estimate used to run about 2% high
+func TestHyperLogLogBiasRange(t *testing.T) {
+	const precision, sketches = 10, 64
+	m := 1 << precision
+	// Two standard errors of the mean of 'sketches' independent estimates
+	tolerance := 2 * 1.04 / math.Sqrt(float64(m)) / math.Sqrt(sketches)
+
+	checkpoints := []int{5 * m / 2, 3 * m, 4 * m, 5 * m}
+	sums := make([]float64, len(checkpoints))
+	for s := 0; s < sketches; s++ {
+		h := NewHyperLogLog(precision)
+		added := 0
+		for c, n := range checkpoints {
+			for ; added < n; added++ {
+				h.Add([]byte(fmt.Sprintf("s%d-%d", s, added)))
+			}
+			sums[c] += float64(h.Count())
+		}
+	}
+	for c, n := range checkpoints {
+		mean := sums[c] / sketches
+		if e := math.Abs(mean-float64(n)) / float64(n); e > tolerance {
+			t.Errorf("%d distinct: mean estimate %.0f, off by %.2f%%, tolerance %.2f%%", n, mean, 100*e, 100*tolerance)
+		}
+	}
+}
+
+func TestHyperLogLogSparse(t *testing.T) {
+	h := NewHyperLogLog(14)
+	added := 0
+	for ; h.Sparse(); added++ {
+		// 4096 entries of 4 bytes take what 16384 registers do
+		if n := len(h.sparse) + len(h.pending); n > 4096 {
+			t.Fatalf("sketch still sparse with %d entries", n)
+		}
+		if got := h.Count(); hllError(got, added) > 0.001 {
+			t.Fatalf("sparse count of %d is %d", added, got)
+		}
+		h.Add([]byte(fmt.Sprintf("v%d", added)))
+	}
+	if added < 4096 {
+		t.Fatalf("sketch left the sparse form after %d items", added)
+	}
+
+	// The dense registers are what adding straight to a dense sketch gives
+	dense := NewHyperLogLog(14)
+	dense.registers = make([]uint8, 1<<14)
+	for i := 0; i < added; i++ {
+		dense.Add([]byte(fmt.Sprintf("v%d", i)))
+	}
+	if !bytes.Equal(h.registers, dense.registers) {
+		t.Fatal("switching to registers gave different registers than adding to them")
+	}
+}
+
+func TestHyperLogLogMerge(t *testing.T) {
+	// Every combination of sparse and dense sketches
+	for _, sizes := range [][2]int{{100, 200}, {100, 50000}, {50000, 100}, {30000, 60000}} {
+		a, b, whole := NewHyperLogLog(14), NewHyperLogLog(14), NewHyperLogLog(14)
+		for i := 0; i < sizes[0]; i++ {
+			item := []byte(fmt.Sprintf("a%d", i))
+			a.Add(item)
+			whole.Add(item)
+		}
+		// Half of b overlaps a
+		for i := 0; i < sizes[1]; i++ {
+			item := []byte(fmt.Sprintf("b%d", i))
+			if i%2 == 0 {
+				item = []byte(fmt.Sprintf("a%d", i))
+			}
+			b.Add(item)
+			whole.Add(item)
+		}
+		if err := a.Merge(b); err != nil {
+			t.Fatal(err)
+		}
+		if a.Count() != whole.Count() {
+			t.Errorf("%v: merged count %d, adding everything to one sketch counts %d", sizes, a.Count(), whole.Count())
+		}
+		if !a.Sparse() && !whole.Sparse() && !bytes.Equal(a.registers, whole.registers) {
+			t.Errorf("%v: merged registers differ from adding everything to one sketch", sizes)
+		}
+	}
+
+	if err := NewHyperLogLog(12).Merge(NewHyperLogLog(14)); !errors.Is(err, ErrHLLPrecision) {
+		t.Errorf("merging different precisions: %v", err)
+	}
+}
+
+func TestHyperLogLogMarshalBinary(t *testing.T) {
+	for _, n := range []int{0, 100, 100000} {
+		h := NewHyperLogLog(12)
+		for i := 0; i < n; i++ {
+			h.Add([]byte(fmt.Sprintf("x%d", i)))
+		}
+		data, err := h.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var loaded HyperLogLog
+		if err := loaded.UnmarshalBinary(data); err != nil {
+			t.Fatalf("%d items: %v", n, err)
+		}
+		if loaded.Count() != h.Count() || loaded.Sparse() != h.Sparse() || loaded.Precision() != 12 {
+			t.Fatalf("%d items: loaded sketch counts %d, want %d", n, loaded.Count(), h.Count())
+		}
+		// The loaded sketch keeps working
+		loaded.Add([]byte("new"))
+		h.Add([]byte("new"))
+		if loaded.Count() != h.Count() {
+			t.Fatalf("%d items: loaded sketch diverged after an Add", n)
+		}
+	}
+
+	small := NewHyperLogLog(12)
+	small.Add([]byte("a"))
+	small.Add([]byte("b"))
+	sparse, _ := small.MarshalBinary()
+	dense := NewHyperLogLog(4)
+	for i := 0; i < 100; i++ {
+		dense.Add([]byte(fmt.Sprint(i)))
+	}
+	denseData, _ := dense.MarshalBinary()
+	swapped := bytes.Clone(sparse)
+	copy(swapped[10:], sparse[14:18])
+	copy(swapped[14:], sparse[10:14])
+
+	for name, corrupt := range map[string][]byte{
+		"empty":          nil,
+		"bad magic":      append([]byte("XXXX"), sparse[4:]...),
+		"precision":      append(append(bytes.Clone(sparse[:4]), 30), sparse[5:]...),
+		"kind":           append(append(bytes.Clone(sparse[:5]), 9), sparse[6:]...),
+		"sparse count":   append(bytes.Clone(sparse), 0, 0, 0, 0),
+		"sparse order":   swapped,
+		"sparse rho":     append(bytes.Clone(sparse[:len(sparse)-1]), sparse[len(sparse)-1]&^(1<<hllRhoBits-1)),
+		"dense length":   denseData[:len(denseData)-1],
+		"dense register": append(bytes.Clone(denseData[:len(denseData)-1]), 64),
+	} {
+		var loaded HyperLogLog
+		if err := loaded.UnmarshalBinary(corrupt); !errors.Is(err, ErrCorruptSketch) {
+			t.Errorf("%s: expected ErrCorruptSketch, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package DataStructures
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Composite keys are encoded "memcomparably": comparing two encodings byte by byte
+// (bytes.Compare, or plain string comparison) gives the same answer as comparing
+// the tuples column by column. That lets a (tenant_id, created_at) index live in
+// newBTree[string, V] or newBTreeFunc[[]byte, V](degree, bytes.Compare).
+//
+// Each column is a tag byte followed by its payload. Columns of different types
+// order by tag, so NULL sorts before every value. Callers should give a column the
+// same Go type in every key
+
+var (
+	ErrUnsupportedKeyType = errors.New("btree: unsupported key column type")
+	ErrNaNKey             = errors.New("btree: NaN cannot be used as a key")
+	ErrCorruptKey         = errors.New("btree: corrupt encoded key")
+)
+
+const (
+	keyTagNull   byte = 0x00
+	keyTagInt    byte = 0x10
+	keyTagUint   byte = 0x11
+	keyTagFloat  byte = 0x20
+	keyTagTime   byte = 0x30
+	keyTagString byte = 0x40
+	keyTagBytes  byte = 0x41
+)
+
+// Strings and byte slices are variable length, so a 0x00 inside them is escaped as
+// 0x00 0xFF and the column ends with 0x00 0x01. A shorter string therefore sorts
+// before any string it is a prefix of
+const (
+	keyEscape     byte = 0x00
+	keyEscapedNul byte = 0xFF
+	keyTerminator byte = 0x01
+)
+
+// EncodeKey encodes the columns of a composite key. Supported column types are
+// nil (NULL), signed and unsigned integers, float32/float64, string, []byte and time.Time
+func EncodeKey(columns ...any) ([]byte, error) {
+	var out []byte
+	for i, column := range columns {
+		var err error
+		if out, err = appendKeyColumn(out, column); err != nil {
+			return nil, fmt.Errorf("column %d: %w", i, err)
+		}
+	}
+	return out, nil
+}
+
+func appendKeyColumn(out []byte, column any) ([]byte, error) {
+	switch v := column.(type) {
+	case nil:
+		return append(out, keyTagNull), nil
+	case int:
+		return appendKeyInt(out, int64(v)), nil
+	case int8:
+		return appendKeyInt(out, int64(v)), nil
+	case int16:
+		return appendKeyInt(out, int64(v)), nil
+	case int32:
+		return appendKeyInt(out, int64(v)), nil
+	case int64:
+		return appendKeyInt(out, v), nil
+	case uint:
+		return appendKeyUint(out, uint64(v)), nil
+	case uint8:
+		return appendKeyUint(out, uint64(v)), nil
+	case uint16:
+		return appendKeyUint(out, uint64(v)), nil
+	case uint32:
+		return appendKeyUint(out, uint64(v)), nil
+	case uint64:
+		return appendKeyUint(out, v), nil
+	case float32:
+		return appendKeyFloat(out, float64(v))
+	case float64:
+		return appendKeyFloat(out, v)
+	case time.Time:
+		return appendKeyTime(out, v), nil
+	case string:
+		return appendKeyBytes(append(out, keyTagString), []byte(v)), nil
+	case []byte:
+		return appendKeyBytes(append(out, keyTagBytes), v), nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKeyType, column)
+	}
+}
+
+// appendKeyInt flips the sign bit so negative numbers sort before positive ones
+func appendKeyInt(out []byte, v int64) []byte {
+	out = append(out, keyTagInt)
+	return binary.BigEndian.AppendUint64(out, uint64(v)^(1<<63))
+}
+
+// appendKeyTime writes seconds like an int64 followed by the nanoseconds, so
+// times outside the years UnixNano can hold (1678 to 2262) still sort in order
+func appendKeyTime(out []byte, v time.Time) []byte {
+	out = append(out, keyTagTime)
+	out = binary.BigEndian.AppendUint64(out, uint64(v.Unix())^(1<<63))
+	return binary.BigEndian.AppendUint32(out, uint32(v.Nanosecond()))
+}
+
+func appendKeyUint(out []byte, v uint64) []byte {
+	out = append(out, keyTagUint)
+	return binary.BigEndian.AppendUint64(out, v)
+}
+
+// appendKeyFloat sets the sign bit of positive numbers and inverts negative ones,
+// which turns IEEE 754 ordering into unsigned integer ordering
+func appendKeyFloat(out []byte, v float64) ([]byte, error) {
+	if math.IsNaN(v) {
+		return nil, ErrNaNKey
+	}
+	if v == 0 {
+		v = 0 // -0 and +0 are the same key
+	}
+	bits := math.Float64bits(v)
+	if bits&(1<<63) != 0 {
+		bits = ^bits
+	} else {
+		bits |= 1 << 63
+	}
+	out = append(out, keyTagFloat)
+	return binary.BigEndian.AppendUint64(out, bits), nil
+}
+
+func appendKeyBytes(out, v []byte) []byte {
+	for _, c := range v {
+		if c == keyEscape {
+			out = append(out, keyEscape, keyEscapedNul)
+			continue
+		}
+		out = append(out, c)
+	}
+	return append(out, keyEscape, keyTerminator)
+}
+
+// DecodeKey reverses EncodeKey. Integers come back as int64 or uint64, floats as
+// float64 and times in UTC
+func DecodeKey(key []byte) ([]any, error) {
+	var columns []any
+	for len(key) > 0 {
+		tag := key[0]
+		key = key[1:]
+		switch tag {
+		case keyTagNull:
+			columns = append(columns, nil)
+		case keyTagTime:
+			if len(key) < 12 {
+				return nil, fmt.Errorf("%w: column %d is truncated", ErrCorruptKey, len(columns))
+			}
+			sec, nsec := int64(binary.BigEndian.Uint64(key)^(1<<63)), binary.BigEndian.Uint32(key[8:])
+			if nsec >= 1e9 {
+				return nil, fmt.Errorf("%w: column %d has %d nanoseconds", ErrCorruptKey, len(columns), nsec)
+			}
+			key = key[12:]
+			columns = append(columns, time.Unix(sec, int64(nsec)).UTC())
+		case keyTagInt, keyTagUint, keyTagFloat:
+			if len(key) < 8 {
+				return nil, fmt.Errorf("%w: column %d is truncated", ErrCorruptKey, len(columns))
+			}
+			bits := binary.BigEndian.Uint64(key)
+			key = key[8:]
+			columns = append(columns, decodeKeyFixed(tag, bits))
+		case keyTagString, keyTagBytes:
+			v, rest, err := decodeKeyBytes(key)
+			if err != nil {
+				return nil, fmt.Errorf("%w: column %d: %v", ErrCorruptKey, len(columns), err)
+			}
+			key = rest
+			if tag == keyTagString {
+				columns = append(columns, string(v))
+			} else {
+				columns = append(columns, v)
+			}
+		default:
+			return nil, fmt.Errorf("%w: unknown tag 0x%02x", ErrCorruptKey, tag)
+		}
+	}
+	return columns, nil
+}
+
+func decodeKeyFixed(tag byte, bits uint64) any {
+	switch tag {
+	case keyTagInt:
+		return int64(bits ^ (1 << 63))
+	case keyTagUint:
+		return bits
+	}
+	if bits&(1<<63) != 0 {
+		bits &^= 1 << 63
+	} else {
+		bits = ^bits
+	}
+	return math.Float64frombits(bits)
+}
+
+func decodeKeyBytes(key []byte) ([]byte, []byte, error) {
+	v := []byte{}
+	for {
+		i := bytes.IndexByte(key, keyEscape)
+		if i < 0 || i+1 >= len(key) {
+			return nil, nil, errors.New("missing terminator")
+		}
+		v = append(v, key[:i]...)
+		switch key[i+1] {
+		case keyTerminator:
+			return v, key[i+2:], nil
+		case keyEscapedNul:
+			v = append(v, keyEscape)
+			key = key[i+2:]
+		default:
+			return nil, nil, fmt.Errorf("bad escape 0x%02x", key[i+1])
+		}
+	}
+}
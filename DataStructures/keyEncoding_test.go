@@ -0,0 +1,214 @@
+package DataStructures
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func mustEncodeKey(t *testing.T, columns ...any) []byte {
+	t.Helper()
+	key, err := EncodeKey(columns...)
+	if err != nil {
+		t.Fatalf("EncodeKey(%v): %v", columns, err)
+	}
+	return key
+}
+
+func TestEncodeKeyPreservesOrder(t *testing.T) {
+	// Each group is listed in ascending order
+	groups := map[string][][]any{
+		"ints":    {{nil}, {int64(math.MinInt64)}, {-1000}, {-1}, {0}, {1}, {255}, {256}, {int64(math.MaxInt64)}},
+		"uints":   {{uint64(0)}, {uint64(1)}, {uint64(1 << 32)}, {uint64(math.MaxUint64)}},
+		"floats":  {{math.Inf(-1)}, {-1e300}, {-2.5}, {-math.SmallestNonzeroFloat64}, {0.0}, {math.SmallestNonzeroFloat64}, {1.5}, {1e300}, {math.Inf(1)}},
+		"strings": {{""}, {"\x00"}, {"\x00\x00"}, {"\x00a"}, {"a"}, {"a\x00"}, {"a\x00b"}, {"a\x01"}, {"ab"}, {"b"}, {"\xff"}},
+		"tuples": {
+			{1, nil},
+			{1, time.Unix(0, 0)},
+			{1, time.Unix(10, 0)},
+			{2, time.Unix(-5, 0)},
+			{2, time.Unix(5, 0)},
+			{10, nil},
+		},
+		"prefix": {{"a"}, {"a", 1}, {"a", 2}, {"ab"}},
+		"times": {
+			{time.Time{}}, // 0001-01-01, far before what UnixNano can hold
+			{time.Date(1600, 1, 1, 0, 0, 0, 0, time.UTC)},
+			{time.Unix(-1, 999999999)},
+			{time.Unix(0, 0)},
+			{time.Unix(0, 1)},
+			{time.Date(2262, 4, 12, 0, 0, 0, 0, time.UTC)},
+			{time.Date(9999, 12, 31, 23, 59, 59, 999999999, time.UTC)},
+		},
+	}
+	for name, keys := range groups {
+		t.Run(name, func(t *testing.T) {
+			for i := 1; i < len(keys); i++ {
+				prev, cur := mustEncodeKey(t, keys[i-1]...), mustEncodeKey(t, keys[i]...)
+				if bytes.Compare(prev, cur) >= 0 {
+					t.Errorf("%v does not encode below %v", keys[i-1], keys[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeKeyNegativeZero(t *testing.T) {
+	if !bytes.Equal(mustEncodeKey(t, math.Copysign(0, -1)), mustEncodeKey(t, 0.0)) {
+		t.Error("-0 and +0 should encode to the same key")
+	}
+}
+
+func TestDecodeKeyRoundTrip(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 30, 0, 42, time.UTC)
+	columns := []any{nil, int64(-7), uint64(7), -0.5, created, "a\x00b", []byte{0, 1, 0xff}, ""}
+	got, err := DecodeKey(mustEncodeKey(t, columns...))
+	if err != nil {
+		t.Fatalf("DecodeKey: %v", err)
+	}
+	if !reflect.DeepEqual(got, columns) {
+		t.Errorf("round trip mismatch:\n got %#v\nwant %#v", got, columns)
+	}
+
+	for _, sentinel := range []time.Time{time.Time{}, time.Date(9999, 12, 31, 23, 59, 59, 999999999, time.UTC)} {
+		got, err := DecodeKey(mustEncodeKey(t, sentinel))
+		if err != nil || len(got) != 1 || !got[0].(time.Time).Equal(sentinel) {
+			t.Errorf("round trip of %v = %v, %v", sentinel, got, err)
+		}
+	}
+}
+
+func TestEncodeKeyErrors(t *testing.T) {
+	if _, err := EncodeKey(1, math.NaN()); !errors.Is(err, ErrNaNKey) {
+		t.Errorf("expected ErrNaNKey, got %v", err)
+	}
+	if _, err := EncodeKey(struct{}{}); !errors.Is(err, ErrUnsupportedKeyType) {
+		t.Errorf("expected ErrUnsupportedKeyType, got %v", err)
+	}
+	valid := mustEncodeKey(t, 1, "tenant")
+	for _, corrupt := range [][]byte{
+		{0x7f},
+		valid[:5],
+		valid[:len(valid)-1],
+		{keyTagString, 'a', keyEscape, 0x02},
+	} {
+		if _, err := DecodeKey(corrupt); !errors.Is(err, ErrCorruptKey) {
+			t.Errorf("DecodeKey(%x): expected ErrCorruptKey, got %v", corrupt, err)
+		}
+	}
+}
+
+// TestEncodedCompositeKeyTree indexes (tenant_id, created_at) through the byte encoding
+func TestEncodedCompositeKeyTree(t *testing.T) {
+	btree := newBTreeFunc[[]byte, string](4, bytes.Compare)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := rand.New(rand.NewSource(9))
+	for _, i := range r.Perm(200) {
+		tenant, minute := i%5, i/5
+		btree.Put(mustEncodeKey(t, tenant, base.Add(time.Duration(minute)*time.Minute)), "row")
+	}
+	if err := btree.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Everything for tenant 3 in [minute 10, minute 20)
+	lo := mustEncodeKey(t, 3, base.Add(10*time.Minute))
+	hi := mustEncodeKey(t, 3, base.Add(20*time.Minute))
+	var minutes []int
+	for c := btree.Range(lo, hi, true, false); c.Valid(); c.Next() {
+		columns, err := DecodeKey(c.Key())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if columns[0] != int64(3) {
+			t.Fatalf("range leaked tenant %v", columns[0])
+		}
+		minutes = append(minutes, int(columns[1].(time.Time).Sub(base)/time.Minute))
+	}
+	want := []int{10, 11, 12, 13, 14, 15, 16, 17, 18, 19}
+	if !equalKeys(minutes, want) {
+		t.Errorf("range = %v, want %v", minutes, want)
+	}
+}
+
+type tenantKey struct {
+	tenant  int
+	created int64
+}
+
+func compareTenantKey(a, b tenantKey) int {
+	if c := cmp.Compare(a.tenant, b.tenant); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.created, b.created)
+}
+
+// TestComparatorTree uses a struct key and a comparator, with no encoding at all
+func TestComparatorTree(t *testing.T) {
+	btree := newBTreeFunc[tenantKey, int](3, compareTenantKey)
+	var keys []tenantKey
+	r := rand.New(rand.NewSource(3))
+	seen := map[tenantKey]bool{}
+	for len(keys) < 150 {
+		k := tenantKey{tenant: r.Intn(4), created: r.Int63n(1000) - 500}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		btree.Put(k, len(keys))
+		keys = append(keys, k)
+	}
+	for _, k := range keys[:50] {
+		btree.Delete(k)
+	}
+	if err := btree.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[tenantKey]bool{}
+	for _, k := range keys[50:] {
+		want[k] = true
+	}
+	for _, k := range keys[:50] {
+		if _, ok := btree.Get(k); ok != want[k] {
+			t.Errorf("Get(%v) found=%v, want %v", k, ok, want[k])
+		}
+	}
+
+	var sorted []tenantKey
+	for k := range want {
+		sorted = append(sorted, k)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return compareTenantKey(sorted[i], sorted[j]) < 0 })
+	var got []tenantKey
+	for c := btree.First(); c.Valid(); c.Next() {
+		got = append(got, c.Key())
+	}
+	if !reflect.DeepEqual(got, sorted) {
+		t.Errorf("cursor order mismatch:\n got %v\nwant %v", got, sorted)
+	}
+}
+
+// TestWideOrderedKeys covers key types Ordered used to exclude
+func TestWideOrderedKeys(t *testing.T) {
+	signed := newBTree[int64, bool](3)
+	unsigned := newBTree[uint64, bool](3)
+	for _, v := range []int64{math.MaxInt64, -3, math.MinInt64, 0, 7} {
+		signed.Insert(v)
+	}
+	for _, v := range []uint64{math.MaxUint64, 0, 1 << 40, 9} {
+		unsigned.Insert(v)
+	}
+	if k, _ := signed.Min(); k != math.MinInt64 {
+		t.Errorf("int64 Min = %d", k)
+	}
+	if k, _ := unsigned.Max(); k != math.MaxUint64 {
+		t.Errorf("uint64 Max = %d", k)
+	}
+}
@@ -0,0 +1,266 @@
+package DataStructures
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// A page file is an array of fixed-size pages addressed by PageID. Page 0 is the
+// header and is never handed out, so PageID 0 doubles as "no page":
+//
+//	magic     [4]byte "BPTP"
+//	version   uint16
+//	          [2]byte unused
+//	pageSize  uint32
+//	pageCount uint64  pages in the file, the header included
+//	freeHead  uint64  first page of the free list, 0 when empty
+//	meta      the rest of the page, owned by whoever uses the pager
+//
+// Freed pages form a linked list, each one keeps the next free page at freeNextOffset
+
+// PageID addresses a page in a page file
+type PageID uint64
+
+const (
+	DefaultPageSize = 4096
+
+	pageFileVersion = 1
+	pageHeaderSize  = 32
+	minPageSize     = 64
+	pageSizeOffset  = 8
+	pageCountOffset = 12
+	pageFreeOffset  = 20
+
+	pageKindFree   = 0
+	freeNextOffset = 8
+)
+
+var pageMagic = [4]byte{'B', 'P', 'T', 'P'}
+
+var (
+	ErrNotAPageFile   = errors.New("pager: file is not a page file")
+	ErrBadPage        = errors.New("pager: page out of range")
+	ErrPageSize       = errors.New("pager: page size does not match")
+	ErrPagerCorrupted = errors.New("pager: corrupt page file")
+)
+
+// Pager reads and writes the pages of one file. Reads and writes of different
+// pages may run concurrently, Allocate and Free are serialized.
+//
+// Every page is written straight to the file, the operating system's page cache
+// is the only cache. Nothing is written atomically, a crash in the middle of an
+// update can leave the file inconsistent
+type Pager struct {
+	file     *os.File
+	pageSize int
+
+	mu        sync.Mutex
+	header    []byte // page 0 as it is on disk
+	pageCount PageID
+	freeHead  PageID
+}
+
+// OpenPager opens the page file at 'path', creating it with pages of 'pageSize'
+// bytes if it does not exist. An existing file keeps its own page size,
+// pass 0 to accept whatever it has or a size to insist on
+func OpenPager(path string, pageSize int) (*Pager, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	p, err := newPager(file, pageSize)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+func newPager(file *os.File, pageSize int) (*Pager, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 {
+		if pageSize == 0 {
+			pageSize = DefaultPageSize
+		}
+		if pageSize < minPageSize {
+			return nil, fmt.Errorf("%w: %d bytes is below the minimum of %d", ErrPageSize, pageSize, minPageSize)
+		}
+		p := &Pager{file: file, pageSize: pageSize, header: make([]byte, pageSize), pageCount: 1}
+		copy(p.header, pageMagic[:])
+		binary.BigEndian.PutUint16(p.header[4:], pageFileVersion)
+		binary.BigEndian.PutUint32(p.header[pageSizeOffset:], uint32(pageSize))
+		return p, p.writeHeader()
+	}
+
+	start := make([]byte, pageHeaderSize)
+	if _, err := file.ReadAt(start, 0); err != nil {
+		if err == io.EOF {
+			return nil, ErrNotAPageFile
+		}
+		return nil, err
+	}
+	if [4]byte(start[:4]) != pageMagic || binary.BigEndian.Uint16(start[4:]) != pageFileVersion {
+		return nil, ErrNotAPageFile
+	}
+	stored := int(binary.BigEndian.Uint32(start[pageSizeOffset:]))
+	if stored < minPageSize {
+		return nil, fmt.Errorf("%w: page size %d", ErrPagerCorrupted, stored)
+	}
+	if pageSize != 0 && pageSize != stored {
+		return nil, fmt.Errorf("%w: file has %d byte pages, not %d", ErrPageSize, stored, pageSize)
+	}
+
+	p := &Pager{file: file, pageSize: stored, header: make([]byte, stored)}
+	if _, err := file.ReadAt(p.header, 0); err != nil {
+		return nil, fmt.Errorf("%w: short header: %v", ErrPagerCorrupted, err)
+	}
+	p.pageCount = PageID(binary.BigEndian.Uint64(p.header[pageCountOffset:]))
+	p.freeHead = PageID(binary.BigEndian.Uint64(p.header[pageFreeOffset:]))
+	if p.pageCount < 1 || p.freeHead >= p.pageCount || info.Size() < int64(p.pageCount)*int64(stored) {
+		return nil, fmt.Errorf("%w: header claims %d pages", ErrPagerCorrupted, p.pageCount)
+	}
+	return p, nil
+}
+
+// PageSize is the size in bytes of every page
+func (p *Pager) PageSize() int {
+	return p.pageSize
+}
+
+// Meta returns a copy of the space in the header page left to the pager's user
+func (p *Pager) Meta() []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]byte(nil), p.header[pageHeaderSize:]...)
+}
+
+// SetMeta stores 'meta' in the header page, it must fit in PageSize minus 32 bytes
+func (p *Pager) SetMeta(meta []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(meta) > p.pageSize-pageHeaderSize {
+		return fmt.Errorf("pager: %d bytes of meta do not fit in the header", len(meta))
+	}
+	copy(p.header[pageHeaderSize:], meta)
+	return p.writeHeader()
+}
+
+// Read fills 'buf', which must be PageSize long, with page 'id'
+func (p *Pager) Read(id PageID, buf []byte) error {
+	if err := p.check(id, buf); err != nil {
+		return err
+	}
+	if _, err := p.file.ReadAt(buf, int64(id)*int64(p.pageSize)); err != nil {
+		return fmt.Errorf("pager: reading page %d: %w", id, err)
+	}
+	return nil
+}
+
+// Write stores 'buf', which must be PageSize long, as page 'id'
+func (p *Pager) Write(id PageID, buf []byte) error {
+	if err := p.check(id, buf); err != nil {
+		return err
+	}
+	if _, err := p.file.WriteAt(buf, int64(id)*int64(p.pageSize)); err != nil {
+		return fmt.Errorf("pager: writing page %d: %w", id, err)
+	}
+	return nil
+}
+
+func (p *Pager) check(id PageID, buf []byte) error {
+	if len(buf) != p.pageSize {
+		return fmt.Errorf("pager: buffer of %d bytes for a %d byte page", len(buf), p.pageSize)
+	}
+	p.mu.Lock()
+	count := p.pageCount
+	p.mu.Unlock()
+	if id == 0 || id >= count {
+		return fmt.Errorf("%w: %d", ErrBadPage, id)
+	}
+	return nil
+}
+
+// Allocate hands out an unused page, reusing freed pages before growing the
+// file. The page's contents are undefined until it is written
+func (p *Pager) Allocate() (PageID, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.freeHead != 0 {
+		id := p.freeHead
+		buf := make([]byte, p.pageSize)
+		if _, err := p.file.ReadAt(buf, int64(id)*int64(p.pageSize)); err != nil {
+			return 0, fmt.Errorf("pager: reading free page %d: %w", id, err)
+		}
+		next := PageID(binary.BigEndian.Uint64(buf[freeNextOffset:]))
+		if buf[0] != pageKindFree || next >= p.pageCount {
+			return 0, fmt.Errorf("%w: free list broken at page %d", ErrPagerCorrupted, id)
+		}
+		p.freeHead = next
+		return id, p.writeHeader()
+	}
+
+	id := p.pageCount
+	// Extend the file now so a reopened pager finds every page it counts
+	if _, err := p.file.WriteAt(make([]byte, p.pageSize), int64(id)*int64(p.pageSize)); err != nil {
+		return 0, fmt.Errorf("pager: growing the file: %w", err)
+	}
+	p.pageCount++
+	return id, p.writeHeader()
+}
+
+// Free returns page 'id' to the free list
+func (p *Pager) Free(id PageID) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if id == 0 || id >= p.pageCount {
+		return fmt.Errorf("%w: %d", ErrBadPage, id)
+	}
+	buf := make([]byte, p.pageSize)
+	buf[0] = pageKindFree
+	binary.BigEndian.PutUint64(buf[freeNextOffset:], uint64(p.freeHead))
+	if _, err := p.file.WriteAt(buf, int64(id)*int64(p.pageSize)); err != nil {
+		return fmt.Errorf("pager: freeing page %d: %w", id, err)
+	}
+	p.freeHead = id
+	return p.writeHeader()
+}
+
+// PageCount is the number of pages in the file, the header and free pages included
+func (p *Pager) PageCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return int(p.pageCount)
+}
+
+// writeHeader stores page 0, the caller holds 'mu' or owns the pager
+func (p *Pager) writeHeader() error {
+	binary.BigEndian.PutUint64(p.header[pageCountOffset:], uint64(p.pageCount))
+	binary.BigEndian.PutUint64(p.header[pageFreeOffset:], uint64(p.freeHead))
+	if _, err := p.file.WriteAt(p.header, 0); err != nil {
+		return fmt.Errorf("pager: writing the header: %w", err)
+	}
+	return nil
+}
+
+// Sync flushes every written page to stable storage
+func (p *Pager) Sync() error {
+	return p.file.Sync()
+}
+
+// Close syncs and closes the file
+func (p *Pager) Close() error {
+	if err := p.file.Sync(); err != nil {
+		p.file.Close()
+		return err
+	}
+	return p.file.Close()
+}
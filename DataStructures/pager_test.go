@@ -0,0 +1,96 @@
+package DataStructures
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPagerAllocateAndReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pages")
+	p, err := OpenPager(path, 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []PageID
+	for i := 0; i < 5; i++ {
+		id, err := p.Allocate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		page := bytes.Repeat([]byte{byte(id)}, 128)
+		if err := p.Write(id, page); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if ids[0] != 1 || ids[4] != 5 || p.PageCount() != 6 {
+		t.Fatalf("allocated %v, %d pages", ids, p.PageCount())
+	}
+
+	// Freed pages come back before the file grows, most recent first
+	if err := p.Free(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Free(4); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetMeta([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err = OpenPager(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if p.PageSize() != 128 || !bytes.HasPrefix(p.Meta(), []byte("hello")) {
+		t.Fatalf("reopened pager: page size %d, meta %q", p.PageSize(), p.Meta()[:8])
+	}
+	buf := make([]byte, 128)
+	if err := p.Read(5, buf); err != nil || buf[0] != 5 {
+		t.Fatalf("page 5 holds %d, %v", buf[0], err)
+	}
+	for _, want := range []PageID{4, 2, 6} {
+		if id, err := p.Allocate(); err != nil || id != want {
+			t.Fatalf("Allocate = %d, %v, want %d", id, err, want)
+		}
+	}
+}
+
+func TestPagerErrors(t *testing.T) {
+	dir := t.TempDir()
+	p, err := OpenPager(filepath.Join(dir, "pages"), 256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 256)
+	for _, id := range []PageID{0, 1, 99} {
+		if err := p.Read(id, buf); !errors.Is(err, ErrBadPage) {
+			t.Errorf("Read(%d): expected ErrBadPage, got %v", id, err)
+		}
+	}
+	if err := p.Write(1, buf[:10]); err == nil {
+		t.Error("short buffer was accepted")
+	}
+	p.Close()
+
+	if _, err := OpenPager(filepath.Join(dir, "pages"), 512); !errors.Is(err, ErrPageSize) {
+		t.Errorf("expected ErrPageSize, got %v", err)
+	}
+	if _, err := OpenPager(filepath.Join(dir, "tiny"), 16); !errors.Is(err, ErrPageSize) {
+		t.Errorf("expected ErrPageSize, got %v", err)
+	}
+	other := filepath.Join(dir, "other")
+	if err := os.WriteFile(other, []byte("some other file entirely, long enough for a header"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenPager(other, 0); !errors.Is(err, ErrNotAPageFile) {
+		t.Errorf("expected ErrNotAPageFile, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package DataStructures
+
+import "math"
+
+const (
+	// scalableGrowth is how much larger each new sub-filter is than the last
+	scalableGrowth = 2
+	// scalableTightening scales the error rate of each new sub-filter, the
+	// rates then sum to at most the target however many filters there are
+	scalableTightening = 0.5
+)
+
+// ScalableBloomFilter is a Bloom filter that does not need its element count
+// up front. It starts with one filter for 'initialCapacity' items and, when
+// that is full, adds a filter twice the size with half the error rate. An item
+// is present if any filter holds it, so the false positive rate is at most the
+// sum of theirs, which the halving keeps below the target.
+//
+// Growing replaces the list of filters, so unlike BloomFilter it is not safe
+// for concurrent use
+type ScalableBloomFilter struct {
+	filters    []*BloomFilter
+	capacities []uint // items each filter was sized for
+	target     float64
+	seed       uint64
+}
+
+// NewScalableBloomFilter creates a filter that keeps its false positive rate
+// under 'falsePositiveRate' however many items are added
+func NewScalableBloomFilter(initialCapacity uint, falsePositiveRate float64) *ScalableBloomFilter {
+	return NewScalableBloomFilterWithSeed(initialCapacity, falsePositiveRate, 0)
+}
+
+// NewScalableBloomFilterWithSeed is NewScalableBloomFilter with its hashes seeded by 'seed'
+func NewScalableBloomFilterWithSeed(initialCapacity uint, falsePositiveRate float64, seed uint64) *ScalableBloomFilter {
+	sf := &ScalableBloomFilter{target: falsePositiveRate, seed: seed}
+	sf.grow(max(initialCapacity, 1))
+	return sf
+}
+
+// grow stacks a new filter for 'capacity' items
+func (sf *ScalableBloomFilter) grow(capacity uint) {
+	rate := sf.target * (1 - scalableTightening) * math.Pow(scalableTightening, float64(len(sf.filters)))
+	sf.filters = append(sf.filters, NewBloomFilterWithSeed(capacity, rate, sf.seed))
+	sf.capacities = append(sf.capacities, capacity)
+}
+
+// Add adds an item to the newest filter, growing first if it is full. Items
+// that already test present are not added again, they would only fill the
+// filter without changing any answer
+func (sf *ScalableBloomFilter) Add(item []byte) {
+	if sf.Contains(item) {
+		return
+	}
+	last := len(sf.filters) - 1
+	if sf.filters[last].ElementCount() >= sf.capacities[last] {
+		sf.grow(sf.capacities[last] * scalableGrowth)
+		last++
+	}
+	sf.filters[last].Add(item)
+}
+
+// Contains checks if an item might be in the set
+func (sf *ScalableBloomFilter) Contains(item []byte) bool {
+	for _, f := range sf.filters {
+		if f.Contains(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// CurrentFalsePositiveRate estimates the chance an absent item tests present
+func (sf *ScalableBloomFilter) CurrentFalsePositiveRate() float64 {
+	miss := 1.0
+	for _, f := range sf.filters {
+		miss *= 1 - f.CurrentFalsePositiveRate()
+	}
+	return 1 - miss
+}
+
+// ElementCount returns the number of distinct items added, as far as the filter could tell
+func (sf *ScalableBloomFilter) ElementCount() uint {
+	n := uint(0)
+	for _, f := range sf.filters {
+		n += f.ElementCount()
+	}
+	return n
+}
+
+// Filters returns how many sub-filters are stacked
+func (sf *ScalableBloomFilter) Filters() int {
+	return len(sf.filters)
+}
+
+// Size returns the number of bits across every sub-filter
+func (sf *ScalableBloomFilter) Size() uint {
+	n := uint(0)
+	for _, f := range sf.filters {
+		n += f.Size()
+	}
+	return n
+}
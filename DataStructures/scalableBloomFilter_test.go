@@ -0,0 +1,86 @@
+package DataStructures
+
+import (
+	"fmt"
+	"testing"
+)
+
+var _ Exister = (*ScalableBloomFilter)(nil)
+
+func TestScalableBloomFilter(t *testing.T) {
+	t.Run("Grows", func(t *testing.T) {
+		sf := NewScalableBloomFilter(100, 0.01)
+		if sf.Filters() != 1 {
+			t.Fatalf("starts with %d filters", sf.Filters())
+		}
+		for i := 0; i < 10000; i++ {
+			sf.Add([]byte(fmt.Sprintf("item-%d", i)))
+		}
+		// 100 + 200 + ... + 6400 = 12700 is the first sum past 10000
+		if sf.Filters() != 7 {
+			t.Errorf("10000 items need 7 filters, got %d", sf.Filters())
+		}
+		for i := 0; i < 10000; i++ {
+			if !sf.Contains([]byte(fmt.Sprintf("item-%d", i))) {
+				t.Fatalf("item-%d went missing", i)
+			}
+		}
+		// Items that were false positives when added are not counted
+		if n := sf.ElementCount(); n > 10000 || n < 9500 {
+			t.Errorf("ElementCount = %d, want close to 10000", n)
+		}
+	})
+
+	t.Run("Holds_Target_Rate", func(t *testing.T) {
+		const target = 0.01
+		sf := NewScalableBloomFilter(1000, target)
+		plain := NewBloomFilter(1000, target)
+		for i := 0; i < 100000; i++ {
+			item := []byte(fmt.Sprintf("in-%d", i))
+			sf.Add(item)
+			plain.Add(item)
+		}
+
+		falsePositives, plainPositives := 0, 0
+		const probes = 100000
+		for i := 0; i < probes; i++ {
+			item := []byte(fmt.Sprintf("out-%d", i))
+			if sf.Contains(item) {
+				falsePositives++
+			}
+			if plain.Contains(item) {
+				plainPositives++
+			}
+		}
+		// The rates of the sub-filters add up to the target, allow for sampling noise
+		if rate := float64(falsePositives) / probes; rate > 1.1*target {
+			t.Errorf("measured false positive rate %v exceeds the target %v", rate, target)
+		}
+		if rate := sf.CurrentFalsePositiveRate(); rate > target {
+			t.Errorf("estimated false positive rate %v exceeds the target %v", rate, target)
+		}
+		// The fixed size filter is overwhelmed by 100 times its expected count
+		if plainPositives < probes/2 {
+			t.Errorf("overfilled plain filter only had %d false positives", plainPositives)
+		}
+	})
+
+	t.Run("Duplicates_Do_Not_Grow", func(t *testing.T) {
+		sf := NewScalableBloomFilter(10, 0.01)
+		for i := 0; i < 1000; i++ {
+			sf.Add([]byte("same"))
+		}
+		if sf.Filters() != 1 || sf.ElementCount() != 1 {
+			t.Fatalf("one item repeated made %d filters and %d elements", sf.Filters(), sf.ElementCount())
+		}
+	})
+
+	t.Run("Zero_Capacity", func(t *testing.T) {
+		sf := NewScalableBloomFilter(0, 0.01)
+		sf.Add([]byte("a"))
+		sf.Add([]byte("b"))
+		if !sf.Contains([]byte("a")) || !sf.Contains([]byte("b")) {
+			t.Fatal("items lost with a zero initial capacity")
+		}
+	})
+}
@@ -2,30 +2,212 @@ package DataStructures
 
 import (
 	"bytes"
+	"cmp"
+	"encoding/binary"
 	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
 )
 
 /*
 This package is for serializing data strucutres/ either to json or to disk
 */
 
-func (node *bNode[T]) Serialize() []byte {
-	var buffer bytes.Buffer
-	encoder := gob.NewEncoder(&buffer)
-	err := encoder.Encode(node)
+// A serialized BTree is a fixed header followed by a gob body:
+//
+//	magic   [4]byte "BPT+"
+//	version uint16
+//	degree  uint16
+//	flags   uint16  treeFlagCounted if the tree keeps subtree counts
+//	length  uint64  body length in bytes
+//	crc     uint32  CRC-32 (IEEE) of the body
+//	body    every node in pre-order, children right after their parent
+//
+// The parent, next and prev pointers and the subtree counts are not stored,
+// they are rebuilt from the order of the nodes
+const (
+	treeFormatVersion = 1
+	treeHeaderSize    = 4 + 2 + 2 + 2 + 8 + 4
+
+	treeFlagCounted = 1 << 0
+)
+
+var treeMagic = [4]byte{'B', 'P', 'T', '+'}
+
+var (
+	ErrNotATree           = errors.New("btree: input is not a serialized tree")
+	ErrUnsupportedVersion = errors.New("btree: unsupported serialization version")
+	ErrCorruptTree        = errors.New("btree: corrupt serialized tree")
+)
+
+// wireNode is how one node is written, gob only sees exported fields
+type wireNode[K any, V any] struct {
+	Leaf     bool
+	Keys     []K
+	Values   []V
+	Children int // number of nodes that follow as this node's children
+}
+
+// WriteTo writes the whole tree to 'w' and returns the number of bytes written.
+// Like Display it needs the tree to itself and waits for other operations
+func (b *BTree[K, V]) WriteTo(w io.Writer) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var body bytes.Buffer
+	enc := gob.NewEncoder(&body)
+	if b.root != nil {
+		if err := writeNode(enc, b.root); err != nil {
+			return 0, err
+		}
+	}
+
+	header := make([]byte, treeHeaderSize)
+	copy(header, treeMagic[:])
+	binary.BigEndian.PutUint16(header[4:], treeFormatVersion)
+	binary.BigEndian.PutUint16(header[6:], b.degree)
+	var flags uint16
+	if b.counted {
+		flags |= treeFlagCounted
+	}
+	binary.BigEndian.PutUint16(header[8:], flags)
+	binary.BigEndian.PutUint64(header[10:], uint64(body.Len()))
+	binary.BigEndian.PutUint32(header[18:], crc32.ChecksumIEEE(body.Bytes()))
+
+	n, err := w.Write(header)
 	if err != nil {
-		panic(err) // Handle serialization error
+		return int64(n), err
+	}
+	m, err := body.WriteTo(w)
+	return int64(n) + m, err
+}
+
+func writeNode[K any, V any](enc *gob.Encoder, node *bNode[K, V]) error {
+	out := wireNode[K, V]{Leaf: node.leaf, Keys: node.keys, Children: len(node.children)}
+	if node.leaf {
+		out.Values = node.values
+	}
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	for _, child := range node.children {
+		if err := writeNode(enc, child); err != nil {
+			return err
+		}
 	}
-	return buffer.Bytes()
+	return nil
 }
 
-func Deserialize[T Ordered](data []byte) *bNode[T] {
-	buffer := bytes.NewBuffer(data)
-	decoder := gob.NewDecoder(buffer)
-	var node bNode[T]
-	err := decoder.Decode(&node)
+// ReadBTree reads a tree written by WriteTo. Input that is truncated, fails its
+// checksum or does not decode to a valid tree of K and V is reported as
+// ErrCorruptTree
+func ReadBTree[K Ordered, V any](r io.Reader) (*BTree[K, V], error) {
+	return ReadBTreeFunc[K, V](r, cmp.Compare[K])
+}
+
+// ReadBTreeFunc is ReadBTree for a tree ordered by 'compare', see newBTreeFunc.
+// The keys are checked against 'compare', a different order is ErrCorruptTree
+func ReadBTreeFunc[K any, V any](r io.Reader, compare func(a, b K) int) (*BTree[K, V], error) {
+	header := make([]byte, treeHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("%w: short header", ErrCorruptTree)
+		}
+		return nil, err
+	}
+	if !bytes.Equal(header[:4], treeMagic[:]) {
+		return nil, ErrNotATree
+	}
+	if version := binary.BigEndian.Uint16(header[4:]); version != treeFormatVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+	degree := binary.BigEndian.Uint16(header[6:])
+	if degree < 3 {
+		return nil, fmt.Errorf("%w: degree %d", ErrCorruptTree, degree)
+	}
+	flags := binary.BigEndian.Uint16(header[8:])
+	if flags&^treeFlagCounted != 0 {
+		return nil, fmt.Errorf("%w: unknown flags %#x", ErrCorruptTree, flags)
+	}
+	length := binary.BigEndian.Uint64(header[10:])
+
+	// ReadAll grows as data arrives, so a corrupt length cannot force a huge allocation
+	body, err := io.ReadAll(io.LimitReader(r, int64(min(length, 1<<62))))
 	if err != nil {
-		panic(err) // Handle deserialization error
+		return nil, err
+	}
+	if uint64(len(body)) != length {
+		return nil, fmt.Errorf("%w: body is %d bytes, header says %d", ErrCorruptTree, len(body), length)
+	}
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(header[18:]) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrCorruptTree)
+	}
+
+	b := &BTree[K, V]{degree: degree, compare: compare, counted: flags&treeFlagCounted != 0}
+	if length == 0 {
+		return b, nil
+	}
+	dec := &treeDecoder[K, V]{tree: b, gob: gob.NewDecoder(bytes.NewReader(body)), remaining: len(body)}
+	if b.root, err = dec.readNode(nil, 0); err != nil {
+		return nil, err
+	}
+	// Rebuild the leaf chain from the pre-order
+	for i := 1; i < len(dec.leaves); i++ {
+		dec.leaves[i-1].next = dec.leaves[i]
+		dec.leaves[i].prev = dec.leaves[i-1]
+	}
+	if b.counted {
+		fillCounts(b.root)
+	}
+
+	if err := b.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptTree, err)
+	}
+	return b, nil
+}
+
+// treeDecoder carries state while ReadBTree rebuilds the nodes
+type treeDecoder[K any, V any] struct {
+	tree      *BTree[K, V]
+	gob       *gob.Decoder
+	leaves    []*bNode[K, V]
+	remaining int // every node takes at least a byte, this bounds the node count
+}
+
+func (d *treeDecoder[K, V]) readNode(parent *bNode[K, V], depth int) (*bNode[K, V], error) {
+	// A tree of degree 3 or more holding every key a 64 bit index can reach is
+	// far shallower than this, anything deeper is a corrupt child count
+	if depth > 64 {
+		return nil, fmt.Errorf("%w: tree is too deep", ErrCorruptTree)
+	}
+	var in wireNode[K, V]
+	if err := d.gob.Decode(&in); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptTree, err)
+	}
+	d.remaining--
+	if in.Children < 0 || in.Children > d.remaining || in.Leaf != (in.Children == 0) {
+		return nil, fmt.Errorf("%w: node with %d children", ErrCorruptTree, in.Children)
+	}
+
+	node := d.tree.newNode(in.Leaf)
+	node.parent = parent
+	node.keys = in.Keys
+	if in.Leaf {
+		if len(in.Values) != len(in.Keys) {
+			return nil, fmt.Errorf("%w: leaf has %d keys but %d values", ErrCorruptTree, len(in.Keys), len(in.Values))
+		}
+		node.values = in.Values
+		d.leaves = append(d.leaves, node)
+		return node, nil
+	}
+	for i := 0; i < in.Children; i++ {
+		child, err := d.readNode(node, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		node.children = append(node.children, child)
 	}
-	return &node
+	return node, nil
 }
@@ -1 +1,204 @@
 package DataStructures
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"testing"
+)
+
+func TestWriteToRoundTrip(t *testing.T) {
+	for _, degree := range []uint16{3, 4, 7} {
+		btree := newCountedBTree[int, string](degree)
+		for k := 0; k < 300; k++ {
+			btree.Put(k, fmt.Sprint("v", k))
+		}
+		for k := 0; k < 300; k += 3 {
+			btree.Delete(k)
+		}
+
+		var buf bytes.Buffer
+		n, err := btree.WriteTo(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != int64(buf.Len()) {
+			t.Fatalf("WriteTo reported %d bytes, wrote %d", n, buf.Len())
+		}
+		loaded, err := ReadBTree[int, string](&buf)
+		if err != nil {
+			t.Fatalf("degree %d: %v", degree, err)
+		}
+		if loaded.degree != degree {
+			t.Fatalf("degree %d came back as %d", degree, loaded.degree)
+		}
+		if got, want := leafKeys(loaded), leafKeys(btree); !equalKeys(got, want) {
+			t.Fatalf("degree %d: loaded keys %v, want %v", degree, got, want)
+		}
+		if got := collectKeysReverse(loaded.Last()); len(got) != 200 {
+			t.Fatalf("degree %d: reverse scan found %d keys", degree, len(got))
+		}
+		if v, ok := loaded.Get(100); !ok || v != "v100" {
+			t.Fatalf("Get(100) = (%q, %v)", v, ok)
+		}
+		if got := loaded.Rank(150); got != btree.Rank(150) {
+			t.Fatalf("Rank(150) = %d, want %d", got, btree.Rank(150))
+		}
+
+		// The loaded tree keeps working
+		for k := 0; k < 300; k += 2 {
+			loaded.Delete(k)
+		}
+		loaded.Put(1000, "x")
+		if err := loaded.Validate(); err != nil {
+			t.Fatalf("degree %d: after updates: %v", degree, err)
+		}
+	}
+}
+
+func TestWriteToRoundTripComparator(t *testing.T) {
+	btree := versionTree()
+	var buf bytes.Buffer
+	if _, err := btree.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	loaded, err := ReadBTreeFunc[versionKey, int](bytes.NewReader(data), compareVersionKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Both trees list the same entries in the same order
+	c := loaded.First()
+	for want := btree.First(); want.Valid(); want.Next() {
+		if !c.Valid() || c.Key() != want.Key() || c.Value() != want.Value() {
+			t.Fatalf("loaded tree differs at %v", want.Key())
+		}
+		c.Next()
+	}
+	if c.Valid() {
+		t.Fatalf("loaded tree has an extra key %v", c.Key())
+	}
+
+	// Read with the opposite order the keys are out of place
+	reversed := func(a, b versionKey) int { return compareVersionKey(b, a) }
+	if _, err := ReadBTreeFunc[versionKey, int](bytes.NewReader(data), reversed); !errors.Is(err, ErrCorruptTree) {
+		t.Errorf("reading with another order: expected ErrCorruptTree, got %v", err)
+	}
+}
+
+func TestWriteToEmpty(t *testing.T) {
+	for _, btree := range []*BTree[int, int]{newBTree[int, int](3), {degree: 5}} {
+		var buf bytes.Buffer
+		if _, err := btree.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := ReadBTree[int, int](&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if loaded.Search(1) || loaded.degree != btree.degree {
+			t.Fatalf("empty tree came back as %+v", loaded)
+		}
+	}
+}
+
+func TestReadBTreeTruncated(t *testing.T) {
+	btree := newBTree[int, int](4)
+	for k := 0; k < 50; k++ {
+		btree.Put(k, k)
+	}
+	var buf bytes.Buffer
+	if _, err := btree.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	for n := 0; n < len(data); n++ {
+		if _, err := ReadBTree[int, int](bytes.NewReader(data[:n])); !errors.Is(err, ErrCorruptTree) {
+			t.Fatalf("%d of %d bytes: expected ErrCorruptTree, got %v", n, len(data), err)
+		}
+	}
+}
+
+func TestReadBTreeCorrupt(t *testing.T) {
+	btree := newBTree[int, int](4)
+	for k := 0; k < 50; k++ {
+		btree.Put(k, k)
+	}
+	var buf bytes.Buffer
+	if _, err := btree.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	for i := range data {
+		if i == 6 || i == 7 {
+			continue // another degree can still describe a valid tree
+		}
+		corrupt := bytes.Clone(data)
+		corrupt[i] ^= 0x41
+		if _, err := ReadBTree[int, int](bytes.NewReader(corrupt)); err == nil {
+			t.Fatalf("flipping byte %d went unnoticed", i)
+		}
+	}
+
+	if _, err := ReadBTree[int, int](bytes.NewReader([]byte("not a tree at all, just text"))); !errors.Is(err, ErrNotATree) {
+		t.Errorf("expected ErrNotATree, got %v", err)
+	}
+	future := bytes.Clone(data)
+	binary.BigEndian.PutUint16(future[4:], treeFormatVersion+1)
+	if _, err := ReadBTree[int, int](bytes.NewReader(future)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("expected ErrUnsupportedVersion, got %v", err)
+	}
+	if _, err := ReadBTree[string, int](bytes.NewReader(data)); !errors.Is(err, ErrCorruptTree) {
+		t.Errorf("reading int keys as strings: expected ErrCorruptTree, got %v", err)
+	}
+}
+
+// frameTree wraps hand written nodes in a valid header, so the checks behind
+// the checksum can be reached
+func frameTree(t *testing.T, degree uint16, nodes ...wireNode[int, int]) []byte {
+	var body bytes.Buffer
+	enc := gob.NewEncoder(&body)
+	for _, node := range nodes {
+		if err := enc.Encode(node); err != nil {
+			t.Fatal(err)
+		}
+	}
+	header := make([]byte, treeHeaderSize)
+	copy(header, treeMagic[:])
+	binary.BigEndian.PutUint16(header[4:], treeFormatVersion)
+	binary.BigEndian.PutUint16(header[6:], degree)
+	binary.BigEndian.PutUint64(header[10:], uint64(body.Len()))
+	binary.BigEndian.PutUint32(header[18:], crc32.ChecksumIEEE(body.Bytes()))
+	return append(header, body.Bytes()...)
+}
+
+func TestReadBTreeCorruptStructure(t *testing.T) {
+	leaf := func(keys ...int) wireNode[int, int] {
+		return wireNode[int, int]{Leaf: true, Keys: keys, Values: keys}
+	}
+	for name, data := range map[string][]byte{
+		"small degree":     frameTree(t, 2, leaf(1)),
+		"missing children": frameTree(t, 3, wireNode[int, int]{Keys: []int{5}, Children: 2}, leaf(1)),
+		"huge child count": frameTree(t, 3, wireNode[int, int]{Keys: []int{5}, Children: 1 << 40}, leaf(1), leaf(5)),
+		"leaf children":    frameTree(t, 3, wireNode[int, int]{Leaf: true, Children: 1}, leaf(1)),
+		"missing values":   frameTree(t, 3, wireNode[int, int]{Leaf: true, Keys: []int{1, 2}}),
+		"unsorted keys":    frameTree(t, 3, leaf(2, 1)),
+		"bad separator":    frameTree(t, 3, wireNode[int, int]{Keys: []int{5}, Children: 2}, leaf(1, 7), leaf(8)),
+	} {
+		if _, err := ReadBTree[int, int](bytes.NewReader(data)); !errors.Is(err, ErrCorruptTree) {
+			t.Errorf("%s: expected ErrCorruptTree, got %v", name, err)
+		}
+	}
+
+	good := frameTree(t, 3, wireNode[int, int]{Keys: []int{5}, Children: 2}, leaf(1, 3), leaf(5, 8))
+	btree, err := ReadBTree[int, int](bytes.NewReader(good))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := leafKeys(btree); !equalKeys(got, []int{1, 3, 5, 8}) {
+		t.Fatalf("hand written tree holds %v", got)
+	}
+}